@@ -41,7 +41,10 @@ func main() {
 	deadmansProbe := factories.NewProbeBuilder().BuildDeadmansSnitch()
 
 	log.Println("register probes ...")
-	probeStore.Add(deadmansProbe)
+	err := probeStore.Add(deadmansProbe)
+	if err != nil {
+		log.Fatalf("could not register the probes: %s", err)
+	}
 
 	log.Println("keeping the http server open for you ...")
 	fmt.Println("Press <Enter> to exit...")
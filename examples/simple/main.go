@@ -50,7 +50,10 @@ func main() {
 		MustBuild()
 
 	log.Println("register probes ...")
-	probeStore.Add(deadmansProbe, dialCheckProbe, httpCheckProbe, customProbe)
+	err := probeStore.Add(deadmansProbe, dialCheckProbe, httpCheckProbe, customProbe)
+	if err != nil {
+		log.Fatalf("could not register the probes: %s", err)
+	}
 
 	log.Println("keeping the http server open for you ...")
 	fmt.Println("Press <Enter> to exit...")
@@ -39,7 +39,10 @@ func main() {
 	probes := factories.NewProbeBuilder().BuildForComponents(healthcheck.ReadinessProbeKind, componentsStatus)
 
 	log.Println("register probes ...")
-	probeStore.Add(probes...)
+	err := probeStore.Add(probes...)
+	if err != nil {
+		log.Fatalf("could not register the probes: %s", err)
+	}
 
 	// Here we are simulating that the component 'foo' changes its status due to outside conditions,
 	// And we're expecting that the Prometheus metric will change accordingly.
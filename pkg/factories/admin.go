@@ -0,0 +1,105 @@
+package factories
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+	"github.com/pkg/errors"
+)
+
+// OverrideRequest is the body of the requests that set an override.
+type OverrideRequest struct {
+	Probe  string                        `json:"probe"`
+	Mode   healthcheck.OverrideMode      `json:"mode"`
+	Status healthcheck.ProbeHealthStatus `json:"status,omitempty"`
+	Reason string                        `json:"reason"`
+
+	// ExpiresIn is a duration, e.g. "30m". The override never expires if it is empty.
+	ExpiresIn string `json:"expires_in,omitempty"`
+}
+
+// NewOverridesEndpointDefinition creates the '/health/overrides' administrative endpoint:
+//   - GET lists the overrides;
+//   - POST sets an override, with an OverrideRequest as body;
+//   - DELETE clears the override of the probe set by the 'probe' query parameter,
+//     and responds with 404 Not Found if the probe is not registered.
+//
+// The requests must be accepted by one of the authenticators.
+// If no authenticators are provided, all the requests are rejected.
+func NewOverridesEndpointDefinition(service healthcheck.Service, authenticators ...Authenticator) healthcheck.EndpointDefinition {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !isAuthenticated(r, authenticators) {
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, service.Overrides())
+
+		case http.MethodPost, http.MethodPut:
+			var req OverrideRequest
+			err := json.NewDecoder(r.Body).Decode(&req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			o := healthcheck.Override{
+				Mode:   req.Mode,
+				Status: req.Status,
+				Reason: req.Reason,
+			}
+
+			if req.ExpiresIn != "" {
+				expiresIn, err := time.ParseDuration(req.ExpiresIn)
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+
+				o.ExpiresAt = time.Now().Add(expiresIn)
+			}
+
+			err = service.SetOverride(req.Probe, o)
+			switch {
+			case errors.Is(err, healthcheck.ErrProbeNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				writeJSON(w, http.StatusOK, o)
+			}
+
+		case http.MethodDelete:
+			name := r.URL.Query().Get("probe")
+			if name == "" {
+				http.Error(w, "the probe query parameter is required", http.StatusBadRequest)
+				return
+			}
+
+			err := service.ClearOverride(name)
+			switch {
+			case errors.Is(err, healthcheck.ErrProbeNotFound):
+				http.Error(w, err.Error(), http.StatusNotFound)
+			case err != nil:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+
+		default:
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		}
+	}
+
+	endpoint := healthcheck.EndpointDefinition{
+		Name:       healthcheck.OverridesName,
+		Endpoint:   healthcheck.OverridesEndpoint,
+		HandleFunc: fn,
+	}
+
+	return endpoint
+}
@@ -0,0 +1,67 @@
+package factories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+)
+
+func TestOverridesEndpointClearsTheOverride(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		statusCode int
+	}{
+		{
+			name:       "registered probe",
+			query:      "?probe=db",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "unknown probe",
+			query:      "?probe=cache",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "empty probe",
+			query:      "",
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probeStore := healthcheck.NewInMemoryProbeStore()
+			err := probeStore.Add(NewProbeBuilder().WithName("db").WithCustomCheck(func(context.Context) error { return nil }).Build())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := healthcheck.NewService(probeStore, healthcheck.NewNoOpMetricsService())
+			err = service.SetOverride("db", healthcheck.Override{Mode: healthcheck.MutedOverride, Reason: "known issue"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			endpoint := NewOverridesEndpointDefinition(service, NewBearerTokenAuthenticator("s3cr3t"))
+
+			req := httptest.NewRequest(http.MethodDelete, endpoint.Endpoint+tt.query, nil)
+			req.Header.Set("Authorization", "Bearer s3cr3t")
+
+			rec := httptest.NewRecorder()
+			endpoint.HandleFunc(rec, req)
+
+			if rec.Code != tt.statusCode {
+				t.Fatalf("expected %d, got %d", tt.statusCode, rec.Code)
+			}
+
+			_, isOverridden := service.Overrides()["db"]
+			if isOverridden != (tt.statusCode != http.StatusNoContent) {
+				t.Fatalf("expected the override to be cleared only for the registered probe, got %t", isOverridden)
+			}
+		})
+	}
+}
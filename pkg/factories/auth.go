@@ -0,0 +1,177 @@
+package factories
+
+import (
+	"context"
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Authenticator decides if a request is allowed to see the detailed health check output.
+type Authenticator interface {
+	Authenticate(r *http.Request) bool
+}
+
+// AuthenticatorFunc allows you to use a function as an Authenticator.
+type AuthenticatorFunc func(r *http.Request) bool
+
+func (fn AuthenticatorFunc) Authenticate(r *http.Request) bool { return fn(r) }
+
+// NewBearerTokenAuthenticator accepts requests with an 'Authorization: Bearer <token>' header matching one of the tokens.
+func NewBearerTokenAuthenticator(tokens ...string) Authenticator {
+	fn := func(r *http.Request) bool {
+		const prefix = "Bearer "
+
+		header := r.Header.Get("Authorization")
+		if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+			return false
+		}
+
+		token := strings.TrimSpace(header[len(prefix):])
+
+		return containsSecret(tokens, token)
+	}
+
+	return AuthenticatorFunc(fn)
+}
+
+// NewBasicAuthAuthenticator accepts requests with basic auth credentials matching the username/password map.
+func NewBasicAuthAuthenticator(credentials map[string]string) Authenticator {
+	fn := func(r *http.Request) bool {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			return false
+		}
+
+		expected, ok := credentials[username]
+		if !ok {
+			return false
+		}
+
+		return subtle.ConstantTimeCompare([]byte(expected), []byte(password)) == 1
+	}
+
+	return AuthenticatorFunc(fn)
+}
+
+// NewClientCertificateAuthenticator accepts requests with a verified TLS client certificate (see WithClientCAFile),
+// whose common name or DNS name is one of the identities.
+//
+// If no identities are provided, any verified client certificate is accepted.
+func NewClientCertificateAuthenticator(identities ...string) Authenticator {
+	fn := func(r *http.Request) bool {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			return false
+		}
+
+		if len(identities) == 0 {
+			return true
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		names := append([]string{cert.Subject.CommonName}, cert.DNSNames...)
+		for _, name := range names {
+			for _, identity := range identities {
+				if name == identity {
+					return true
+				}
+			}
+		}
+
+		return false
+	}
+
+	return AuthenticatorFunc(fn)
+}
+
+// NewCIDRAuthenticator accepts requests whose source address is in one of the CIDR ranges.
+//
+// Note: the source address is the address of the connection, so proxy headers like X-Forwarded-For are not trusted.
+func NewCIDRAuthenticator(cidrs ...string) (Authenticator, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cidr %q", cidr)
+		}
+
+		networks = append(networks, network)
+	}
+
+	fn := func(r *http.Request) bool {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return false
+		}
+
+		for _, network := range networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	return AuthenticatorFunc(fn), nil
+}
+
+func containsSecret(secrets []string, s string) bool {
+	var found bool
+	for _, secret := range secrets {
+		if subtle.ConstantTimeCompare([]byte(secret), []byte(s)) == 1 {
+			found = true
+		}
+	}
+
+	return found
+}
+
+func isAuthenticated(r *http.Request, authenticators []Authenticator) bool {
+	for _, a := range authenticators {
+		if a.Authenticate(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type authorizationKeyType string
+
+const authorizationKey = authorizationKeyType("authorized")
+
+// NewAuthMiddleware marks the requests accepted by any of the authenticators as authorized.
+//
+// The health check endpoints respond to all requests with the status code,
+// but only the authorized requests get the detailed output (e.g. the error messages).
+// Without this middleware, all the requests get the detailed output.
+func NewAuthMiddleware(next http.Handler, authenticators ...Authenticator) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		isAuthorized := isAuthenticated(r, authenticators)
+
+		ctx := context.WithValue(r.Context(), authorizationKey, isAuthorized)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+
+	return http.HandlerFunc(fn)
+}
+
+// IsAuthorized reports if the request may see the detailed output.
+// Requests that didn't go through NewAuthMiddleware are authorized.
+func IsAuthorized(r *http.Request) bool {
+	isAuthorized, ok := r.Context().Value(authorizationKey).(bool)
+	if !ok {
+		return true
+	}
+
+	return isAuthorized
+}
@@ -0,0 +1,242 @@
+package factories
+
+import (
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+)
+
+const dashboardRefreshInterval = 10 * time.Second
+
+// dashboardTemplate is self-contained (no external assets), so the page works in isolated networks.
+var dashboardTemplate = template.Must(template.New("dashboard").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="{{ .RefreshSeconds }}">
+<title>Health check</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 2em; }
+th, td { text-align: left; padding: 0.4em 0.8em; border-bottom: 1px solid #ddd; vertical-align: top; }
+.status { font-weight: bold; padding: 0.1em 0.5em; border-radius: 0.3em; color: #fff; }
+.healthy { background: #2e7d32; }
+.unhealthy { background: #c62828; }
+.unknown { background: #757575; }
+.error { font-family: monospace; white-space: pre-wrap; color: #c62828; }
+.flapping { color: #ef6c00; font-weight: bold; }
+.override { color: #1565c0; font-style: italic; }
+.severity { font-size: 0.8em; padding: 0.1em 0.4em; border: 1px solid #999; border-radius: 0.3em; }
+.metadata { font-size: 0.85em; color: #555; }
+form { display: inline; }
+</style>
+</head>
+<body>
+<h1>Health check</h1>
+<p>Generated at {{ .GeneratedAt }}, refreshed every {{ .RefreshSeconds }} seconds.
+{{ if .CanRun }}<form method="post"><button type="submit">Run all probes</button></form>{{ end }}</p>
+{{ range .Kinds }}
+<h2>{{ .Kind }}</h2>
+<table>
+<tr><th>Probe</th><th>Status</th><th>Last error</th><th>Duration</th><th>Last check</th><th>History</th><th></th></tr>
+{{ range .Probes }}
+<tr>
+<td>{{ .Name }}{{ if .Severity }} <span class="severity">{{ .Severity }}</span>{{ end }}
+{{ if .Description }}<div class="metadata">{{ .Description }}</div>{{ end }}
+{{ if .Owner }}<div class="metadata">Owner: {{ .Owner }}</div>{{ end }}
+{{ if .RunbookURL }}<div class="metadata"><a href="{{ .RunbookURL }}">Runbook</a></div>{{ end }}</td>
+<td><span class="status {{ .StatusClass }}">{{ .Status }}</span>{{ if .Flapping }} <span class="flapping">flapping</span>{{ end }}{{ if .Override }} <span class="override">{{ .Override }}</span>{{ end }}</td>
+<td class="error">{{ .Error }}</td>
+<td>{{ .Duration }}</td>
+<td>{{ .LastCheck }}</td>
+<td><svg width="{{ .SparklineWidth }}" height="16">{{ range .History }}<rect x="{{ .X }}" y="0" width="5" height="16" fill="{{ .Color }}"><title>{{ .Title }}</title></rect>{{ end }}</svg></td>
+<td>{{ if $.CanRun }}<form method="post"><input type="hidden" name="probe" value="{{ .Name }}"><button type="submit">Run</button></form>{{ end }}</td>
+</tr>
+{{ end }}
+</table>
+{{ else }}
+<p>No probes are registered.</p>
+{{ end }}
+</body>
+</html>
+`))
+
+type dashboardData struct {
+	GeneratedAt    string
+	RefreshSeconds int
+	CanRun         bool
+	Kinds          []dashboardKind
+}
+
+type dashboardKind struct {
+	Kind   healthcheck.ProbeKind
+	Probes []dashboardProbe
+}
+
+type dashboardProbe struct {
+	Name           string
+	Description    string
+	Owner          string
+	RunbookURL     string
+	Severity       string
+	Status         string
+	StatusClass    string
+	Flapping       bool
+	Override       string
+	Error          string
+	Duration       string
+	LastCheck      string
+	SparklineWidth int
+	History        []dashboardBar
+}
+
+type dashboardBar struct {
+	X     int
+	Color string
+	Title string
+}
+
+func statusClass(status healthcheck.ProbeHealthStatus) string {
+	switch status {
+	case healthcheck.HealthyStatus:
+		return "healthy"
+	case healthcheck.UnhealthyStatus:
+		return "unhealthy"
+	default:
+		return "unknown"
+	}
+}
+
+func statusColor(status healthcheck.ProbeHealthStatus) string {
+	switch status {
+	case healthcheck.HealthyStatus:
+		return "#2e7d32"
+	case healthcheck.UnhealthyStatus:
+		return "#c62828"
+	default:
+		return "#757575"
+	}
+}
+
+func newDashboardData(service healthcheck.Service, withDetails bool) dashboardData {
+	const barWidth = 6
+
+	history := service.History()
+	kinds := map[healthcheck.ProbeKind][]dashboardProbe{}
+
+	for _, r := range service.LastResults() {
+		p := dashboardProbe{
+			Name:        r.Probe.Name,
+			Status:      string(r.Probe.Health),
+			StatusClass: statusClass(r.Probe.Health),
+			Flapping:    r.Flapping,
+			LastCheck:   "never",
+		}
+
+		if p.Status == "" {
+			p.Status = "unknown"
+		}
+
+		if r.Override != nil {
+			p.Override = fmt.Sprintf("%s: %s", r.Override.Mode, r.Override.Reason)
+			if !r.Override.ExpiresAt.IsZero() {
+				p.Override += fmt.Sprintf(" (until %s)", r.Override.ExpiresAt.Format(time.RFC3339))
+			}
+		}
+
+		if !r.StartedAt.IsZero() {
+			p.LastCheck = r.StartedAt.Format(time.RFC3339)
+			p.Duration = r.Duration.String()
+		}
+
+		if withDetails && r.Err != nil {
+			p.Error = r.Err.Error()
+		}
+
+		if withDetails {
+			p.Description = r.Probe.Metadata.Description
+			p.Owner = r.Probe.Metadata.Owner
+			p.RunbookURL = r.Probe.Metadata.RunbookURL
+			p.Severity = string(r.Probe.Metadata.Severity)
+		}
+
+		for i, e := range history[r.Probe.Name] {
+			p.History = append(p.History, dashboardBar{
+				X:     i * barWidth,
+				Color: statusColor(e.Status),
+				Title: e.Time.Format(time.RFC3339) + " " + string(e.Status),
+			})
+		}
+		p.SparklineWidth = len(p.History) * barWidth
+
+		kinds[r.Probe.Kind] = append(kinds[r.Probe.Kind], p)
+	}
+
+	data := dashboardData{
+		GeneratedAt:    time.Now().Format(time.RFC3339),
+		RefreshSeconds: int(dashboardRefreshInterval.Seconds()),
+		CanRun:         withDetails,
+	}
+
+	for kind, probes := range kinds {
+		sort.Slice(probes, func(i, j int) bool { return probes[i].Name < probes[j].Name })
+		data.Kinds = append(data.Kinds, dashboardKind{Kind: kind, Probes: probes})
+	}
+
+	sort.Slice(data.Kinds, func(i, j int) bool { return data.Kinds[i].Kind < data.Kinds[j].Kind })
+
+	return data
+}
+
+// NewDashboardEndpointDefinition creates the '/health/dashboard' endpoint,
+// an HTML page with the last results of all the probes, grouped by kind.
+//
+// The page refreshes itself, and it can re-run a single probe or all of them (with a POST request).
+// The error messages and the metadata of the probes are only shown to the authorized requests (see NewAuthMiddleware),
+// and only the authorized requests can re-run the probes.
+func NewDashboardEndpointDefinition(service healthcheck.Service) healthcheck.EndpointDefinition {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			if !IsAuthorized(r) {
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			var err error
+			if name := r.FormValue("probe"); name != "" {
+				_, err = service.ExecuteProbesByName(r.Context(), name)
+			} else {
+				_, err = service.ExecuteAllProbes(r.Context())
+			}
+
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+
+			http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+			return
+		}
+
+		data := newDashboardData(service, IsAuthorized(r))
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		err := dashboardTemplate.Execute(w, data)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+
+	endpoint := healthcheck.EndpointDefinition{
+		Name:       healthcheck.DashboardName,
+		Endpoint:   healthcheck.DashboardEndpoint,
+		HandleFunc: fn,
+	}
+
+	return endpoint
+}
@@ -0,0 +1,66 @@
+package factories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+)
+
+func TestDashboardRunsTheProbesForAuthorizedRequests(t *testing.T) {
+	tests := []struct {
+		name       string
+		token      string
+		statusCode int
+		executions int32
+	}{
+		{
+			name:       "authorized",
+			token:      "s3cr3t",
+			statusCode: http.StatusSeeOther,
+			executions: 1,
+		},
+		{
+			name:       "unauthorized",
+			token:      "wrong",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var executions int32
+			probeStore := healthcheck.NewInMemoryProbeStore()
+			err := probeStore.Add(NewProbeBuilder().WithName("db").WithCustomCheck(func(context.Context) error {
+				atomic.AddInt32(&executions, 1)
+				return nil
+			}).Build())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := healthcheck.NewService(probeStore, healthcheck.NewNoOpMetricsService())
+			endpoint := NewDashboardEndpointDefinition(service)
+			handler := NewAuthMiddleware(http.HandlerFunc(endpoint.HandleFunc), NewBearerTokenAuthenticator("s3cr3t"))
+
+			req := httptest.NewRequest(http.MethodPost, endpoint.Endpoint, strings.NewReader("probe=db"))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.statusCode {
+				t.Fatalf("expected %d, got %d", tt.statusCode, rec.Code)
+			}
+
+			if got := atomic.LoadInt32(&executions); got != tt.executions {
+				t.Fatalf("expected %d executions, got %d", tt.executions, got)
+			}
+		})
+	}
+}
@@ -1,9 +1,7 @@
 package factories
 
 import (
-	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
@@ -39,6 +37,13 @@ func GetEndpointDefinitions(service healthcheck.Service) []healthcheck.EndpointD
 		}
 	}
 
+	historyEndpoint := healthcheck.EndpointDefinition{
+		Name:       healthcheck.HistoryName,
+		Endpoint:   healthcheck.HistoryEndpoint,
+		HandleFunc: getHistoryFn(service),
+	}
+	endpoints = append(endpoints, historyEndpoint)
+
 	return endpoints
 }
 
@@ -67,41 +72,45 @@ func getProbeExecutionFns(service healthcheck.Service) map[healthcheck.ProbeKind
 				w.WriteHeader(http.StatusInternalServerError)
 				_, err := w.Write([]byte(err.Error()))
 				if err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
 					fmt.Println(err)
 				}
-			}
-
-			var isProbeCheckFailed bool
-			errMessages := map[string]string{}
-			for _, executionResult := range executionResults {
-				if executionResult.Probe.Health == healthcheck.UnhealthyStatus {
-					isProbeCheckFailed = true
-					errMessages[executionResult.Probe.Name] = executionResult.Err.Error()
-				}
-			}
 
-			if !isProbeCheckFailed {
-				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 
-			w.WriteHeader(http.StatusServiceUnavailable)
+			writeExecutionResults(w, r, executionResults)
+		}
 
-			jsonStr, err := json.Marshal(errMessages)
-			if err != nil {
-				log.Printf("Error: %s\n", err.Error())
-			}
+		probeFns[k] = fn
+	}
 
-			_, err = w.Write(jsonStr)
-			if err != nil {
-				w.WriteHeader(http.StatusInternalServerError)
-				log.Println(err)
+	return probeFns
+}
+
+// getHistoryFn responds with the recent results of the probes set by the 'probe' query parameter(s),
+// e.g. '/health/history?probe=foo&probe=bar', or of all the probes if none is set.
+func getHistoryFn(service healthcheck.Service) func(w http.ResponseWriter, r *http.Request) {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		names := r.URL.Query()["probe"]
+
+		history := service.History(names...)
+		if len(names) > 0 && len(history) == 0 {
+			http.Error(w, "probe not found", http.StatusNotFound)
+			return
+		}
+
+		if !IsAuthorized(r) {
+			for name, entries := range history {
+				for i := range entries {
+					entries[i].Error = ""
+				}
+
+				history[name] = entries
 			}
 		}
 
-		probeFns[k] = fn
+		writeJSON(w, http.StatusOK, history)
 	}
 
-	return probeFns
+	return fn
 }
@@ -2,22 +2,76 @@ package factories
 
 import (
 	"context"
-	"fmt"
+	"crypto/tls"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
 )
 
+const defaultPort = 5090
+
 type HTTPServerBuilder interface {
 	WithPort(port int) HTTPServerBuilder
+
+	// WithHost sets the host (or IP address) the server binds to.
+	// The server listens on all interfaces if no host is set.
+	WithHost(host string) HTTPServerBuilder
+
+	// WithUnixSocket makes the server listen on a unix domain socket instead of a TCP port.
+	// The socket file is created with the given permission bits.
+	// A socket left at the path by a previous run is replaced, but Listen returns an error for any other file.
+	WithUnixSocket(path string, perm os.FileMode) HTTPServerBuilder
+
+	// WithListener makes the server use a caller-provided listener,
+	// e.g. one inherited through socket activation.
+	WithListener(l net.Listener) HTTPServerBuilder
+
+	// WithTLSCertificate makes the server use TLS with the certificate and key files.
+	// The files are reloaded when they change, so rotated certificates are used without a restart.
+	WithTLSCertificate(certFile, keyFile string) HTTPServerBuilder
+
+	// WithTLSConfig makes the server use TLS with the given configuration.
+	// It can be combined with WithTLSCertificate and WithClientCAFile.
+	WithTLSConfig(cfg *tls.Config) HTTPServerBuilder
+
+	// WithClientCAFile enables mutual TLS:
+	// the clients must present a certificate signed by one of the CAs in the bundle.
+	// The bundle is reloaded when it changes.
+	// It requires a server certificate, set with WithTLSCertificate or WithTLSConfig.
+	WithClientCAFile(caFile string) HTTPServerBuilder
+
 	WithHandler(handler http.Handler) HTTPServerBuilder
 	WithBaseContext(baseContextFn func(net.Listener) context.Context) HTTPServerBuilder
 	Build(ctx context.Context) *http.Server
+
+	// Listen creates the listener the server should serve on,
+	// based on the listener, unix socket, or host and port that were set.
+	//
+	// The result can be passed to healthcheck.StartHTTPServer.
+	//
+	// It returns an error if TLS is enabled without a server certificate (e.g. only WithClientCAFile is set).
+	Listen() (net.Listener, error)
 }
 
 type serverBuilder struct {
 	httpHandler http.Handler
+	host        string
 	port        int
 	baseContext func(l net.Listener) context.Context
+
+	socketPath string
+	socketPerm os.FileMode
+	listener   net.Listener
+
+	tlsConfig    *tls.Config
+	certFile     string
+	keyFile      string
+	clientCAFile string
 }
 
 func (s *serverBuilder) WithPort(port int) HTTPServerBuilder {
@@ -26,6 +80,44 @@ func (s *serverBuilder) WithPort(port int) HTTPServerBuilder {
 	return s
 }
 
+func (s *serverBuilder) WithHost(host string) HTTPServerBuilder {
+	s.host = host
+
+	return s
+}
+
+func (s *serverBuilder) WithUnixSocket(path string, perm os.FileMode) HTTPServerBuilder {
+	s.socketPath = path
+	s.socketPerm = perm
+
+	return s
+}
+
+func (s *serverBuilder) WithListener(l net.Listener) HTTPServerBuilder {
+	s.listener = l
+
+	return s
+}
+
+func (s *serverBuilder) WithTLSCertificate(certFile, keyFile string) HTTPServerBuilder {
+	s.certFile = certFile
+	s.keyFile = keyFile
+
+	return s
+}
+
+func (s *serverBuilder) WithTLSConfig(cfg *tls.Config) HTTPServerBuilder {
+	s.tlsConfig = cfg
+
+	return s
+}
+
+func (s *serverBuilder) WithClientCAFile(caFile string) HTTPServerBuilder {
+	s.clientCAFile = caFile
+
+	return s
+}
+
 func (s *serverBuilder) WithHandler(handler http.Handler) HTTPServerBuilder {
 	s.httpHandler = handler
 
@@ -40,7 +132,6 @@ func (s *serverBuilder) WithBaseContext(baseContextFn func(net.Listener) context
 
 func (s *serverBuilder) Build(ctx context.Context) *http.Server {
 	if s.port == 0 {
-		const defaultPort = 5090
 		s.WithPort(defaultPort)
 	}
 
@@ -62,14 +153,140 @@ func (s *serverBuilder) Build(ctx context.Context) *http.Server {
 	}
 
 	httpServer := &http.Server{
-		Addr:        fmt.Sprintf(":%d", s.port),
+		Addr:        s.addr(),
 		Handler:     s.httpHandler,
 		BaseContext: s.baseContext,
 	}
 
+	if s.tlsConfig != nil || s.certFile != "" || s.clientCAFile != "" {
+		reloader := &certificateReloader{
+			certFile:     s.certFile,
+			keyFile:      s.keyFile,
+			clientCAFile: s.clientCAFile,
+		}
+
+		httpServer.TLSConfig = newTLSConfig(s.tlsConfig, reloader)
+	}
+
 	return httpServer
 }
 
+func (s *serverBuilder) Listen() (net.Listener, error) {
+	err := s.validateTLS()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.listener != nil {
+		return s.listener, nil
+	}
+
+	if s.socketPath == "" {
+		return net.Listen("tcp", s.addr())
+	}
+
+	// remove a socket file left behind by a previous run, but never another kind of file
+	var fi os.FileInfo
+	fi, err = os.Lstat(s.socketPath)
+	switch {
+	case err == nil && fi.Mode()&os.ModeSocket == 0:
+		return nil, errors.Errorf("could not listen on unix socket %s: the file exists and is not a socket", s.socketPath)
+	case err == nil:
+		err = os.Remove(s.socketPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not remove unix socket %s", s.socketPath)
+		}
+	case !os.IsNotExist(err):
+		return nil, errors.Wrapf(err, "could not check unix socket %s", s.socketPath)
+	}
+
+	// the socket is created in a private directory, and moved to its path once its permissions are set,
+	// so it is never reachable with the default permissions
+	dir, err := os.MkdirTemp(filepath.Dir(s.socketPath), ".socket-")
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create unix socket %s", s.socketPath)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	tmpPath := filepath.Join(dir, "socket")
+	l, err := net.Listen("unix", tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// the socket is removed from its final path on close
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	ul := &unixSocketListener{Listener: l, path: s.socketPath}
+
+	if s.socketPerm != 0 {
+		err = os.Chmod(tmpPath, s.socketPerm)
+		if err != nil {
+			_ = l.Close()
+			return nil, errors.Wrapf(err, "could not set permissions on unix socket %s", s.socketPath)
+		}
+	}
+
+	err = os.Rename(tmpPath, s.socketPath)
+	if err != nil {
+		_ = l.Close()
+		return nil, errors.Wrapf(err, "could not create unix socket %s", s.socketPath)
+	}
+
+	return ul, nil
+}
+
+// unixSocketListener removes the socket file when it is closed.
+type unixSocketListener struct {
+	net.Listener
+	path string
+	once sync.Once
+}
+
+func (l *unixSocketListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: l.path, Net: "unix"}
+}
+
+func (l *unixSocketListener) Close() error {
+	err := l.Listener.Close()
+	l.once.Do(func() { _ = os.Remove(l.path) })
+
+	return err
+}
+
+// validateTLS returns an error if TLS is enabled (e.g. with WithClientCAFile) without a server certificate.
+func (s *serverBuilder) validateTLS() error {
+	if s.tlsConfig == nil && s.certFile == "" && s.clientCAFile == "" {
+		return nil
+	}
+
+	if s.certFile != "" {
+		return nil
+	}
+
+	if s.tlsConfig != nil && (len(s.tlsConfig.Certificates) > 0 || s.tlsConfig.GetCertificate != nil) {
+		return nil
+	}
+
+	return errors.New("tls is enabled without a server certificate: use WithTLSCertificate, or a tls.Config with certificates")
+}
+
+func (s *serverBuilder) addr() string {
+	if s.listener != nil {
+		return s.listener.Addr().String()
+	}
+
+	if s.socketPath != "" {
+		return s.socketPath
+	}
+
+	port := s.port
+	if port == 0 {
+		port = defaultPort
+	}
+
+	return net.JoinHostPort(s.host, strconv.Itoa(port))
+}
+
 func NewServerBuilder() HTTPServerBuilder {
 	b := &serverBuilder{}
 
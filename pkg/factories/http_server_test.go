@@ -0,0 +1,95 @@
+package factories
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// shortTempDir returns a directory with a path short enough for a unix socket.
+func shortTempDir(t *testing.T) string {
+	t.Helper()
+
+	dir, err := os.MkdirTemp("", "hc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestServerBuilderRemovesTheUnixSocketOnShutdown(t *testing.T) {
+	socketPath := filepath.Join(shortTempDir(t), "health.sock")
+
+	b := NewServerBuilder().
+		WithUnixSocket(socketPath, 0o600).
+		WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	l, err := b.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := b.Build(context.Background())
+	go func() { _ = s.Serve(l) }()
+
+	fi, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fi.Mode()&os.ModeSocket == 0 || fi.Mode().Perm() != 0o600 {
+		t.Fatalf("expected a socket with the permissions 0600, got %s", fi.Mode())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+
+	resp, err := client.Get("http://unix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err = s.Shutdown(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = os.Lstat(socketPath)
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected the socket to be removed, got %v", err)
+	}
+}
+
+func TestServerBuilderRejectsAnExistingFileAsUnixSocket(t *testing.T) {
+	socketPath := filepath.Join(shortTempDir(t), "health.sock")
+
+	err := os.WriteFile(socketPath, []byte("data"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewServerBuilder().WithUnixSocket(socketPath, 0o600).Listen()
+	if err == nil {
+		t.Fatal("expected an error for a path that is not a socket")
+	}
+
+	content, err := os.ReadFile(socketPath)
+	if err != nil || string(content) != "data" {
+		t.Fatalf("expected the file to be kept, got %q, %v", content, err)
+	}
+}
@@ -26,6 +26,28 @@ type ProbeBuilder interface {
 	// WithName sets a friendly name for the probe.
 	WithName(n string) ProbeBuilder
 
+	// WithHiddenDetails replaces the error message of the probe with a generic one in the responses.
+	WithHiddenDetails() ProbeBuilder
+
+	// WithMinInterval sets the minimum interval between two executions of the probe.
+	// Within the interval, the last result of the probe is returned instead.
+	// A negative interval makes the probe always execute, even if the Service has a minimum interval.
+	WithMinInterval(d time.Duration) ProbeBuilder
+
+	// WithDescription, WithOwner, WithRunbookURL, WithSeverity, and WithLabel set the metadata of the probe,
+	// which is shown in the detailed responses and in the dashboard.
+	WithDescription(description string) ProbeBuilder
+	WithOwner(owner string) ProbeBuilder
+	WithRunbookURL(url string) ProbeBuilder
+	WithSeverity(severity healthcheck.Severity) ProbeBuilder
+	WithLabel(key, value string) ProbeBuilder
+
+	// WithHTTPClient sets the client used by WithHTTPGetCheck and WithRemoteHealthcheckCheck
+	// (e.g. to use TLS, a proxy, or an instrumented transport that traces the requests),
+	// so it must be called before them. The default timeout is used if the client has none,
+	// and WithHTTPGetCheck doesn't follow the redirects if the client has no CheckRedirect.
+	WithHTTPClient(client *http.Client) ProbeBuilder
+
 	// WithCustomCheck allows you to define your own function that is to be executed.
 	WithCustomCheck(fn healthcheck.ProbeCheckFn) ProbeBuilder
 
@@ -34,6 +56,11 @@ type ProbeBuilder interface {
 	WithHTTPGetCheck(url string) ProbeBuilder
 	WithTCPDialWithTimeoutCheck(address string) ProbeBuilder
 
+	// WithRemoteHealthcheckCheck calls an endpoint of another instance of this library (e.g. 'http://my-service:5090/ready'),
+	// and reports the remote probes as the children of this probe.
+	// The probe fails if the remote instance is unhealthy.
+	WithRemoteHealthcheckCheck(endpointURL string) ProbeBuilder
+
 	// Build the probe as requested.
 	//
 	// The ProbeKind is set to CustomProbeKind by default.
@@ -63,6 +90,7 @@ type ProbeBuilder interface {
 
 type probeBuilder struct {
 	defaultTimeout time.Duration
+	httpClient     *http.Client
 	probe          *healthcheck.Probe
 }
 
@@ -89,6 +117,57 @@ func (b *probeBuilder) WithName(n string) ProbeBuilder {
 	return b
 }
 
+func (b *probeBuilder) WithHiddenDetails() ProbeBuilder {
+	b.probe.HideDetails = true
+
+	return b
+}
+
+func (b *probeBuilder) WithMinInterval(d time.Duration) ProbeBuilder {
+	b.probe.MinInterval = d
+
+	return b
+}
+
+func (b *probeBuilder) WithDescription(description string) ProbeBuilder {
+	b.probe.Metadata.Description = description
+
+	return b
+}
+
+func (b *probeBuilder) WithOwner(owner string) ProbeBuilder {
+	b.probe.Metadata.Owner = owner
+
+	return b
+}
+
+func (b *probeBuilder) WithRunbookURL(url string) ProbeBuilder {
+	b.probe.Metadata.RunbookURL = url
+
+	return b
+}
+
+func (b *probeBuilder) WithSeverity(severity healthcheck.Severity) ProbeBuilder {
+	b.probe.Metadata.Severity = severity
+
+	return b
+}
+
+func (b *probeBuilder) WithLabel(key, value string) ProbeBuilder {
+	if b.probe.Metadata.Labels == nil {
+		b.probe.Metadata.Labels = map[string]string{}
+	}
+	b.probe.Metadata.Labels[key] = value
+
+	return b
+}
+
+func (b *probeBuilder) WithHTTPClient(client *http.Client) ProbeBuilder {
+	b.httpClient = client
+
+	return b
+}
+
 func (b *probeBuilder) WithCustomCheck(fn healthcheck.ProbeCheckFn) ProbeBuilder {
 	b.probe.CheckFn = fn
 
@@ -101,10 +180,15 @@ func (b *probeBuilder) WithDatabaseConnectionCheck(database *sql.DB) ProbeBuilde
 		defer cancel()
 
 		if database == nil {
-			return fmt.Errorf("database is nil")
+			return &healthcheck.CheckError{Code: healthcheck.ConfigurationErrorCode, Message: "database is nil"}
+		}
+
+		err := database.PingContext(ctx)
+		if err != nil {
+			return healthcheck.NewCheckError(healthcheck.ConnectionErrorCode, "could not ping the database", err)
 		}
 
-		return database.PingContext(ctx)
+		return nil
 	}
 
 	b.probe.CheckFn = fn
@@ -126,11 +210,13 @@ func (b *probeBuilder) WithDNSResolveCheck(host string) ProbeBuilder {
 
 		addrs, err := resolver.LookupHost(ctx, host)
 		if err != nil {
-			return err
+			e := healthcheck.NewCheckError(healthcheck.DNSErrorCode, "could not resolve host", err)
+			e.Retryable = true
+			return e
 		}
 
 		if len(addrs) < 1 {
-			return fmt.Errorf("could not resolve host")
+			return &healthcheck.CheckError{Code: healthcheck.DNSErrorCode, Message: "could not resolve host", Retryable: true}
 		}
 
 		return nil
@@ -147,19 +233,26 @@ func (b *probeBuilder) WithDNSResolveCheck(host string) ProbeBuilder {
 }
 
 func (b *probeBuilder) WithHTTPGetCheck(url string) ProbeBuilder {
-	client := http.Client{
-		Timeout: b.defaultTimeout,
+	client := b.newHTTPClient()
 
-		// don't follow redirects
-		CheckRedirect: func(*http.Request, []*http.Request) error {
+	// don't follow redirects, unless the client set with WithHTTPClient has its own policy
+	if client.CheckRedirect == nil {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
 			return http.ErrUseLastResponse
-		},
+		}
 	}
 
-	fn := func(context.Context) error {
-		resp, err := client.Get(url)
+	fn := func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 		if err != nil {
-			return err
+			return &healthcheck.CheckError{Code: healthcheck.ConfigurationErrorCode, Message: "invalid url", Err: err}
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			e := healthcheck.NewCheckError(healthcheck.ConnectionErrorCode, "could not get the url", err)
+			e.Retryable = true
+			return e
 		}
 
 		defer func(Body io.ReadCloser) {
@@ -170,7 +263,13 @@ func (b *probeBuilder) WithHTTPGetCheck(url string) ProbeBuilder {
 		}(resp.Body)
 
 		if resp.StatusCode >= http.StatusBadRequest {
-			return errors.Wrapf(healthcheck.ErrCheckFailed, "status code: %d", resp.StatusCode)
+			return &healthcheck.CheckError{
+				Code:      healthcheck.StatusCodeErrorCode,
+				Message:   "unexpected status code",
+				Observed:  resp.StatusCode,
+				Threshold: http.StatusBadRequest,
+				Retryable: resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests,
+			}
 		}
 
 		return nil
@@ -187,10 +286,16 @@ func (b *probeBuilder) WithHTTPGetCheck(url string) ProbeBuilder {
 }
 
 func (b *probeBuilder) WithTCPDialWithTimeoutCheck(address string) ProbeBuilder {
-	fn := func(context.Context) error {
-		conn, err := net.DialTimeout("tcp", address, b.defaultTimeout)
+	dialer := net.Dialer{
+		Timeout: b.defaultTimeout,
+	}
+
+	fn := func(ctx context.Context) error {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
 		if err != nil {
-			return err
+			e := healthcheck.NewCheckError(healthcheck.ConnectionErrorCode, "could not dial", err)
+			e.Retryable = true
+			return e
 		}
 
 		return conn.Close()
@@ -206,6 +311,55 @@ func (b *probeBuilder) WithTCPDialWithTimeoutCheck(address string) ProbeBuilder
 	return b
 }
 
+func (b *probeBuilder) WithRemoteHealthcheckCheck(endpointURL string) ProbeBuilder {
+	client := b.newHTTPClient()
+
+	fn := func(ctx context.Context) error {
+		resp, err := getRemoteHealth(ctx, client, endpointURL)
+		if err != nil {
+			var checkErr *healthcheck.CheckError
+			if errors.As(err, &checkErr) {
+				return err
+			}
+
+			e := healthcheck.NewCheckError(healthcheck.ConnectionErrorCode, "could not get the remote health", err)
+			e.Retryable = true
+			return e
+		}
+
+		healthcheck.ReportChildResults(ctx, newRemoteExecutionResults(resp.Probes)...)
+
+		if resp.Status == healthcheck.UnhealthyStatus {
+			return &healthcheck.CheckError{Code: healthcheck.RemoteUnhealthyErrorCode, Message: "remote instance is unhealthy"}
+		}
+
+		return nil
+	}
+
+	b.probe.CheckFn = fn
+
+	if strings.TrimSpace(b.probe.Name) == "" {
+		const defaultName = "remote healthcheck"
+		b.WithName(defaultName)
+	}
+
+	return b
+}
+
+// newHTTPClient returns a copy of the client set with WithHTTPClient, so the checks can adjust it.
+func (b *probeBuilder) newHTTPClient() *http.Client {
+	client := &http.Client{}
+	if b.httpClient != nil {
+		*client = *b.httpClient
+	}
+
+	if client.Timeout == 0 {
+		client.Timeout = b.defaultTimeout
+	}
+
+	return client
+}
+
 func (b *probeBuilder) Build() healthcheck.Probe {
 	b.probe.Name = strings.TrimSpace(b.probe.Name)
 
@@ -213,7 +367,17 @@ func (b *probeBuilder) Build() healthcheck.Probe {
 		b.probe.Kind = healthcheck.CustomProbeKind
 	}
 
-	return *b.probe
+	p := *b.probe
+
+	// the probes built from the same builder must not share their labels
+	if b.probe.Metadata.Labels != nil {
+		p.Metadata.Labels = make(map[string]string, len(b.probe.Metadata.Labels))
+		for k, v := range b.probe.Metadata.Labels {
+			p.Metadata.Labels[k] = v
+		}
+	}
+
+	return p
 }
 
 func (b *probeBuilder) MustBuild() healthcheck.Probe {
@@ -0,0 +1,99 @@
+package factories
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbeBuilderCopiesTheLabels(t *testing.T) {
+	builder := NewProbeBuilder().
+		WithName("db").
+		WithCustomCheck(func(context.Context) error { return nil }).
+		WithLabel("team", "payments")
+
+	first := builder.Build()
+	second := builder.WithLabel("team", "billing").WithLabel("tier", "1").Build()
+	first.Metadata.Labels["region"] = "eu"
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected map[string]string
+	}{
+		{
+			name:     "first probe",
+			labels:   first.Metadata.Labels,
+			expected: map[string]string{"team": "payments", "region": "eu"},
+		},
+		{
+			name:     "second probe",
+			labels:   second.Metadata.Labels,
+			expected: map[string]string{"team": "billing", "tier": "1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if len(tt.labels) != len(tt.expected) {
+				t.Fatalf("expected %v, got %v", tt.expected, tt.labels)
+			}
+
+			for k, v := range tt.expected {
+				if tt.labels[k] != v {
+					t.Fatalf("expected %v, got %v", tt.expected, tt.labels)
+				}
+			}
+		})
+	}
+}
+
+func TestProbeBuilderHTTPGetCheckRedirects(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer target.Close()
+
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	tests := []struct {
+		name        string
+		client      *http.Client
+		expectError bool
+	}{
+		{
+			name:        "default client",
+			client:      nil,
+			expectError: false,
+		},
+		{
+			name:        "client without a redirect policy",
+			client:      &http.Client{},
+			expectError: false,
+		},
+		{
+			name: "client with a redirect policy",
+			client: &http.Client{
+				CheckRedirect: func(*http.Request, []*http.Request) error { return nil },
+			},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			builder := NewProbeBuilder().WithName("web")
+			if tt.client != nil {
+				builder.WithHTTPClient(tt.client)
+			}
+			p := builder.WithHTTPGetCheck(redirect.URL).Build()
+
+			err := p.Execute(context.Background())
+			if (err != nil) != tt.expectError {
+				t.Fatalf("expected an error: %t, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package factories
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+	"github.com/pkg/errors"
+)
+
+// maxRemoteHealthResponseSize limits the body read from a remote instance, which may be misbehaving.
+const maxRemoteHealthResponseSize = 1 << 20
+
+// getRemoteHealth calls a health check endpoint of another instance of this library, and parses its verbose output.
+func getRemoteHealth(ctx context.Context, client *http.Client, endpointURL string) (HealthResponse, error) {
+	u, err := url.Parse(endpointURL)
+	if err != nil {
+		return HealthResponse{}, err
+	}
+
+	q := u.Query()
+	q.Set("verbose", "")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return HealthResponse{}, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return HealthResponse{}, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		err := Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(resp.Body)
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusServiceUnavailable:
+	case http.StatusNoContent:
+		// the remote instance doesn't support the verbose output
+		return HealthResponse{Status: healthcheck.HealthyStatus}, nil
+	default:
+		return HealthResponse{}, &healthcheck.CheckError{
+			Code:      healthcheck.StatusCodeErrorCode,
+			Message:   "unexpected status code",
+			Observed:  resp.StatusCode,
+			Retryable: resp.StatusCode >= http.StatusInternalServerError,
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteHealthResponseSize+1))
+	if err != nil {
+		return HealthResponse{}, err
+	}
+
+	if len(body) > maxRemoteHealthResponseSize {
+		return HealthResponse{}, &healthcheck.CheckError{
+			Code:      healthcheck.ThresholdErrorCode,
+			Message:   "remote health check response is too large",
+			Threshold: maxRemoteHealthResponseSize,
+		}
+	}
+
+	var healthResponse HealthResponse
+	err = json.Unmarshal(body, &healthResponse)
+	if err == nil && healthResponse.Status != "" {
+		return healthResponse, nil
+	}
+
+	// the remote instance doesn't support the verbose output, so it responded with the map of the unhealthy probes
+	errMessages := map[string]string{}
+	err = json.Unmarshal(body, &errMessages)
+	if err != nil {
+		return HealthResponse{}, errors.Wrap(err, "could not parse the remote health check response")
+	}
+
+	healthResponse = HealthResponse{
+		Status: healthcheck.UnhealthyStatus,
+		Probes: make([]ProbeResponse, 0, len(errMessages)),
+	}
+
+	for name, message := range errMessages {
+		healthResponse.Probes = append(healthResponse.Probes, ProbeResponse{
+			Name:   name,
+			Status: healthcheck.UnhealthyStatus,
+			Error:  message,
+		})
+	}
+
+	sort.Slice(healthResponse.Probes, func(i, j int) bool {
+		return healthResponse.Probes[i].Name < healthResponse.Probes[j].Name
+	})
+
+	return healthResponse, nil
+}
+
+func newRemoteExecutionResults(probes []ProbeResponse) []healthcheck.ExecutionResult {
+	executionResults := make([]healthcheck.ExecutionResult, 0, len(probes))
+	for _, p := range probes {
+		r := healthcheck.ExecutionResult{
+			Probe: healthcheck.Probe{
+				Kind:   p.Kind,
+				Name:   p.Name,
+				Health: p.Status,
+			},
+			Flapping: p.Flapping,
+			Children: newRemoteExecutionResults(p.Children),
+		}
+
+		if p.Metadata != nil {
+			r.Probe.Metadata = *p.Metadata
+		}
+
+		if p.Code != "" {
+			r.Err = &remoteCheckError{
+				message: p.Error,
+				err: &healthcheck.CheckError{
+					Code:      p.Code,
+					Observed:  p.Observed,
+					Threshold: p.Threshold,
+					Retryable: p.Retryable,
+				},
+			}
+		} else if p.Error != "" {
+			r.Err = errors.New(p.Error)
+		} else if p.Status == healthcheck.UnhealthyStatus {
+			r.Err = healthcheck.ErrCheckFailed
+		}
+
+		executionResults = append(executionResults, r)
+	}
+
+	return executionResults
+}
+
+// remoteCheckError keeps the error message of a remote probe as it is,
+// while its CheckError can still be matched with errors.As.
+type remoteCheckError struct {
+	message string
+	err     *healthcheck.CheckError
+}
+
+func (e *remoteCheckError) Error() string {
+	if e.message == "" {
+		return e.err.Error()
+	}
+
+	return e.message
+}
+
+func (e *remoteCheckError) Unwrap() error { return e.err }
+
+// NewFederatedEndpointDefinition creates the '/health/federated' endpoint,
+// which aggregates the health check endpoints of other instances of this library into a single view.
+//
+// The instances map has the name of the instance as key, and the URL of its endpoint as value
+// (e.g. "orders": "http://orders:5090/ready").
+// Each instance is reported as a probe, with the remote probes as its children.
+func NewFederatedEndpointDefinition(instances map[string]string) healthcheck.EndpointDefinition {
+	return NewFederatedEndpointDefinitionWithClient(instances, nil)
+}
+
+// NewFederatedEndpointDefinitionWithClient creates the '/health/federated' endpoint (see NewFederatedEndpointDefinition),
+// which calls the instances with the client, e.g. to use TLS or a proxy.
+func NewFederatedEndpointDefinitionWithClient(instances map[string]string, client *http.Client) healthcheck.EndpointDefinition {
+	probeStore := healthcheck.NewInMemoryProbeStore()
+	for name, endpointURL := range instances {
+		p := NewProbeBuilder().
+			WithName(name).
+			WithHTTPClient(client).
+			WithRemoteHealthcheckCheck(endpointURL).
+			Build()
+
+		probeStore.Upsert(p)
+	}
+
+	service := healthcheck.NewService(probeStore, healthcheck.NewNoOpMetricsService())
+
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		executionResults, err := service.ExecuteAllProbes(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp := newHealthResponse(executionResults, IsAuthorized(r))
+
+		statusCode := http.StatusOK
+		if resp.Status == healthcheck.UnhealthyStatus {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, statusCode, resp)
+	}
+
+	endpoint := healthcheck.EndpointDefinition{
+		Name:       healthcheck.FederatedName,
+		Endpoint:   healthcheck.FederatedEndpoint,
+		HandleFunc: fn,
+	}
+
+	return endpoint
+}
@@ -0,0 +1,168 @@
+package factories
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+	"github.com/pkg/errors"
+)
+
+// HealthResponse is the detailed output of a health check endpoint,
+// returned when the request has the 'verbose' query parameter (e.g. '/ready?verbose').
+type HealthResponse struct {
+	Status healthcheck.ProbeHealthStatus `json:"status"`
+	Probes []ProbeResponse               `json:"probes"`
+}
+
+type ProbeResponse struct {
+	Name   string                        `json:"name"`
+	Kind   healthcheck.ProbeKind         `json:"kind"`
+	Status healthcheck.ProbeHealthStatus `json:"status"`
+
+	// Flapping is set if the probe changes its status too often.
+	Flapping bool `json:"flapping,omitempty"`
+
+	// Error is only set for the authorized requests.
+	Error string `json:"error,omitempty"`
+
+	// Code is the cause of the failure (see healthcheck.CheckError).
+	Code      healthcheck.ErrorCode `json:"code,omitempty"`
+	Retryable bool                  `json:"retryable,omitempty"`
+
+	// Observed and Threshold are only set for the authorized requests.
+	Observed  interface{} `json:"observed,omitempty"`
+	Threshold interface{} `json:"threshold,omitempty"`
+
+	// Metadata is only set for the authorized requests.
+	Metadata *healthcheck.ProbeMetadata `json:"metadata,omitempty"`
+
+	// Override is set if the probe is disabled, muted, or its status is forced.
+	Override *healthcheck.Override `json:"override,omitempty"`
+
+	// Children are the nested results, e.g. the probes of a remote health check instance.
+	Children []ProbeResponse `json:"children,omitempty"`
+}
+
+func newHealthResponse(executionResults []healthcheck.ExecutionResult, withDetails bool) HealthResponse {
+	resp := HealthResponse{
+		Status: healthcheck.HealthyStatus,
+		Probes: make([]ProbeResponse, 0, len(executionResults)),
+	}
+
+	for _, executionResult := range executionResults {
+		p := newProbeResponse(executionResult, withDetails)
+		if executionResult.IsUnhealthy() {
+			resp.Status = healthcheck.UnhealthyStatus
+		}
+
+		resp.Probes = append(resp.Probes, p)
+	}
+
+	return resp
+}
+
+func newProbeResponse(executionResult healthcheck.ExecutionResult, withDetails bool) ProbeResponse {
+	p := ProbeResponse{
+		Name:     executionResult.Probe.Name,
+		Kind:     executionResult.Probe.Kind,
+		Status:   executionResult.Probe.Health,
+		Flapping: executionResult.Flapping,
+		Override: executionResult.Override,
+	}
+
+	if withDetails && !executionResult.Probe.Metadata.IsZero() {
+		metadata := executionResult.Probe.Metadata
+		p.Metadata = &metadata
+	}
+
+	if executionResult.Err != nil {
+		p.Code = healthcheck.ErrorCodeOf(executionResult.Err)
+
+		var checkErr *healthcheck.CheckError
+		isCheckErr := errors.As(executionResult.Err, &checkErr)
+		if isCheckErr {
+			p.Retryable = checkErr.Retryable
+		}
+
+		if withDetails {
+			p.Error = executionResult.Err.Error()
+
+			if isCheckErr && !executionResult.Probe.HideDetails {
+				p.Observed = checkErr.Observed
+				p.Threshold = checkErr.Threshold
+			}
+		}
+	}
+
+	for _, child := range executionResult.Children {
+		p.Children = append(p.Children, newProbeResponse(child, withDetails))
+	}
+
+	return p
+}
+
+// writeExecutionResults responds with:
+//   - 204 No Content if all the probes are healthy, or 503 Service Unavailable
+//     with a map of the unhealthy probes and their errors, sorted by probe name;
+//   - the HealthResponse if the request is verbose, with the probes in the order of the ExecutionResult(s).
+//
+// The error messages are replaced by the probe status for the requests that are not authorized.
+func writeExecutionResults(w http.ResponseWriter, r *http.Request, executionResults []healthcheck.ExecutionResult) {
+	isAuthorized := IsAuthorized(r)
+	_, isVerbose := r.URL.Query()["verbose"]
+
+	resp := newHealthResponse(executionResults, isAuthorized)
+
+	statusCode := http.StatusServiceUnavailable
+	if resp.Status == healthcheck.HealthyStatus {
+		statusCode = http.StatusNoContent
+		if isVerbose {
+			statusCode = http.StatusOK
+		}
+	}
+
+	if isVerbose {
+		writeJSON(w, statusCode, resp)
+		return
+	}
+
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return
+	}
+
+	errMessages := map[string]string{}
+	for i, executionResult := range executionResults {
+		if !executionResult.IsUnhealthy() {
+			continue
+		}
+
+		p := resp.Probes[i]
+		if isAuthorized {
+			errMessages[p.Name] = p.Error
+		} else {
+			errMessages[p.Name] = string(p.Status)
+		}
+	}
+
+	writeJSON(w, statusCode, errMessages)
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	jsonStr, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("Error: %s\n", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	_, err = w.Write(jsonStr)
+	if err != nil {
+		log.Println(err)
+	}
+}
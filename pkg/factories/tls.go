@@ -0,0 +1,207 @@
+package factories
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// certificateReloader loads the certificate, key, and client CA files,
+// and reloads them when they are modified (e.g. rotated by cert-manager),
+// so that the server doesn't need to be restarted.
+type certificateReloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+
+	mu          sync.Mutex
+	cert        *tls.Certificate
+	certModTime time.Time
+	clientCAs   *x509.CertPool
+	caModTime   time.Time
+}
+
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := latestModTime(r.certFile, r.keyFile)
+	if err != nil {
+		return r.fallbackCertificate(err)
+	}
+
+	if r.cert != nil && !modTime.After(r.certModTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return r.fallbackCertificate(err)
+	}
+
+	r.cert = &cert
+	r.certModTime = modTime
+
+	return r.cert, nil
+}
+
+func (r *certificateReloader) fallbackCertificate(err error) (*tls.Certificate, error) {
+	err = errors.Wrap(err, "could not load tls certificate")
+	if r.cert == nil {
+		return nil, err
+	}
+
+	log.Printf("%s; using the previously loaded certificate\n", err)
+
+	return r.cert, nil
+}
+
+func (r *certificateReloader) ClientCAs() (*x509.CertPool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	modTime, err := latestModTime(r.clientCAFile)
+	if err != nil {
+		return r.fallbackClientCAs(err)
+	}
+
+	if r.clientCAs != nil && !modTime.After(r.caModTime) {
+		return r.clientCAs, nil
+	}
+
+	pem, err := os.ReadFile(r.clientCAFile)
+	if err != nil {
+		return r.fallbackClientCAs(err)
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return r.fallbackClientCAs(errors.Errorf("no certificates found in %s", r.clientCAFile))
+	}
+
+	r.clientCAs = pool
+	r.caModTime = modTime
+
+	return r.clientCAs, nil
+}
+
+func (r *certificateReloader) fallbackClientCAs(err error) (*x509.CertPool, error) {
+	err = errors.Wrap(err, "could not load client CA bundle")
+	if r.clientCAs == nil {
+		return nil, err
+	}
+
+	log.Printf("%s; using the previously loaded CA bundle\n", err)
+
+	return r.clientCAs, nil
+}
+
+func latestModTime(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+
+	return latest, nil
+}
+
+// newTLSConfig returns the base config, with the certificate and the client CAs of the reloader.
+//
+// The client certificates are verified in VerifyConnection against the reloaded CAs,
+// so the config prepared by the http.Server (e.g. with the 'h2' protocol) is used as it is,
+// and the GetConfigForClient of the base config is kept.
+func newTLSConfig(base *tls.Config, r *certificateReloader) *tls.Config {
+	var cfg *tls.Config
+	if base != nil {
+		cfg = base.Clone()
+	} else {
+		cfg = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	if r.certFile != "" {
+		cfg.GetCertificate = r.GetCertificate
+	}
+
+	if r.clientCAFile == "" {
+		return cfg
+	}
+
+	requireClientCertificate(cfg, r)
+
+	if getConfigForClient := cfg.GetConfigForClient; getConfigForClient != nil {
+		cfg.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			c, err := getConfigForClient(hello)
+			if err != nil || c == nil {
+				return c, err
+			}
+
+			c = c.Clone()
+			requireClientCertificate(c, r)
+
+			return c, nil
+		}
+	}
+
+	return cfg
+}
+
+// requireClientCertificate makes the config verify the client certificates against the CAs of the reloader,
+// after the VerifyConnection already set.
+func requireClientCertificate(cfg *tls.Config, r *certificateReloader) {
+	verifyConnection := cfg.VerifyConnection
+
+	cfg.ClientAuth = tls.RequireAnyClientCert
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		err := verifyClientCertificate(cs, r)
+		if err != nil {
+			return err
+		}
+
+		if verifyConnection != nil {
+			return verifyConnection(cs)
+		}
+
+		return nil
+	}
+}
+
+func verifyClientCertificate(cs tls.ConnectionState, r *certificateReloader) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("tls: client didn't provide a certificate")
+	}
+
+	clientCAs, err := r.ClientCAs()
+	if err != nil {
+		return err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return errors.Wrap(err, "tls: could not verify the client certificate")
+	}
+
+	return nil
+}
@@ -0,0 +1,317 @@
+package factories
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCertificate creates a certificate signed by the parent, or a self-signed CA if the parent is nil.
+func newTestCertificate(t *testing.T, serial int64, parent *testCertificate) *testCertificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+
+	signerCert, signerKey := tmpl, key
+	if parent != nil {
+		signerCert, signerKey = parent.cert, parent.key
+	} else {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signerCert, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+
+	return c
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+
+	cert, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+// writeTestCertificate writes the files with the given modification time, so a rotation is detected
+// even within the resolution of the file system timestamps.
+func writeTestCertificate(t *testing.T, c *testCertificate, certFile, keyFile string, modTime time.Time) {
+	t.Helper()
+
+	for file, content := range map[string][]byte{certFile: c.certPEM, keyFile: c.keyPEM} {
+		err := os.WriteFile(file, content, 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Chtimes(file, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func startTestTLSServer(t *testing.T, b HTTPServerBuilder) string {
+	t.Helper()
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b.WithListener(tcpListener).WithHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	l, err := b.Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := b.Build(context.Background())
+	go func() { _ = s.ServeTLS(l, "", "") }()
+	t.Cleanup(func() { _ = s.Close() })
+
+	return l.Addr().String()
+}
+
+func peerSerial(t *testing.T, addr string, cfg *tls.Config) int64 {
+	t.Helper()
+
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+func TestServerBuilderReloadsTheCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+
+	ca := newTestCertificate(t, 1, nil)
+	writeTestCertificate(t, newTestCertificate(t, 2, ca), certFile, keyFile, time.Now())
+
+	addr := startTestTLSServer(t, NewServerBuilder().WithTLSCertificate(certFile, keyFile))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	cfg := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+
+	if serial := peerSerial(t, addr, cfg); serial != 2 {
+		t.Fatalf("expected the certificate 2, got %d", serial)
+	}
+
+	writeTestCertificate(t, newTestCertificate(t, 3, ca), certFile, keyFile, time.Now().Add(time.Minute))
+
+	if serial := peerSerial(t, addr, cfg); serial != 3 {
+		t.Fatalf("expected the rotated certificate 3, got %d", serial)
+	}
+
+	// an invalid rotation keeps the previous certificate
+	err := os.WriteFile(certFile, []byte("not a certificate"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(2 * time.Minute)
+	_ = os.Chtimes(certFile, modTime, modTime)
+
+	if serial := peerSerial(t, addr, cfg); serial != 3 {
+		t.Fatalf("expected the previous certificate 3, got %d", serial)
+	}
+}
+
+func TestServerBuilderRequiresClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca := newTestCertificate(t, 1, nil)
+	writeTestCertificate(t, newTestCertificate(t, 2, ca), certFile, keyFile, time.Now())
+
+	err := os.WriteFile(caFile, ca.certPEM, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr := startTestTLSServer(t, NewServerBuilder().WithTLSCertificate(certFile, keyFile).WithClientCAFile(caFile))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "localhost",
+			Certificates: certs,
+		}}}
+	}
+
+	_, err = newClient().Get("https://" + addr)
+	if err == nil {
+		t.Fatal("expected the request without a client certificate to fail")
+	}
+
+	otherCA := newTestCertificate(t, 10, nil)
+	_, err = newClient(newTestCertificate(t, 11, otherCA).tlsCertificate(t)).Get("https://" + addr)
+	if err == nil {
+		t.Fatal("expected the request with an untrusted client certificate to fail")
+	}
+
+	resp, err := newClient(newTestCertificate(t, 3, ca).tlsCertificate(t)).Get("https://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerBuilderListenRejectsTLSWithoutCertificate(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+
+	getConfigForClient := func(*tls.ClientHelloInfo) (*tls.Config, error) { return nil, nil }
+
+	tests := []struct {
+		name    string
+		builder HTTPServerBuilder
+	}{
+		{
+			name:    "client CA only",
+			builder: NewServerBuilder().WithClientCAFile(caFile),
+		},
+		{
+			name:    "config without certificate",
+			builder: NewServerBuilder().WithTLSConfig(&tls.Config{}),
+		},
+		{
+			name:    "config for client only",
+			builder: NewServerBuilder().WithTLSConfig(&tls.Config{GetConfigForClient: getConfigForClient}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Listen()
+			if err == nil {
+				t.Fatal("expected an error for TLS without a server certificate")
+			}
+		})
+	}
+}
+
+func TestServerBuilderMutualTLSKeepsTheServerConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	ca := newTestCertificate(t, 1, nil)
+	writeTestCertificate(t, newTestCertificate(t, 2, ca), certFile, keyFile, time.Now())
+
+	err := os.WriteFile(caFile, ca.certPEM, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var helloServerNames []string
+	var mu sync.Mutex
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			mu.Lock()
+			helloServerNames = append(helloServerNames, hello.ServerName)
+			mu.Unlock()
+
+			return nil, nil
+		},
+	}
+
+	b := NewServerBuilder().WithTLSConfig(cfg).WithTLSCertificate(certFile, keyFile).WithClientCAFile(caFile)
+	addr := startTestTLSServer(t, b)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	conn, err := tls.Dial("tcp", addr, &tls.Config{
+		RootCAs:      roots,
+		ServerName:   "localhost",
+		Certificates: []tls.Certificate{newTestCertificate(t, 3, ca).tlsCertificate(t)},
+		NextProtos:   []string{"h2", "http/1.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if protocol := conn.ConnectionState().NegotiatedProtocol; protocol != "h2" {
+		t.Fatalf("expected the h2 protocol, got %q", protocol)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(helloServerNames) != 1 || helloServerNames[0] != "localhost" {
+		t.Fatalf("expected the GetConfigForClient of the config to be called, got %v", helloServerNames)
+	}
+}
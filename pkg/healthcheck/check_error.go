@@ -0,0 +1,138 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// ErrorCode is the machine-readable cause of a failed check.
+type ErrorCode string
+
+const (
+	UnknownErrorCode ErrorCode = "unknown"
+
+	// TimeoutErrorCode is set when the check didn't complete in time.
+	TimeoutErrorCode ErrorCode = "timeout"
+
+	// ConnectionErrorCode is set when the dependency could not be reached.
+	ConnectionErrorCode ErrorCode = "connection_failed"
+
+	// DNSErrorCode is set when a host could not be resolved.
+	DNSErrorCode ErrorCode = "dns_resolution_failed"
+
+	// StatusCodeErrorCode is set when a dependency responded with an unexpected (e.g. HTTP) status code.
+	StatusCodeErrorCode ErrorCode = "unexpected_status_code"
+
+	// ThresholdErrorCode is set when an observed value is beyond its threshold.
+	ThresholdErrorCode ErrorCode = "threshold_exceeded"
+
+	// RemoteUnhealthyErrorCode is set when a remote health check instance is unhealthy.
+	RemoteUnhealthyErrorCode ErrorCode = "remote_unhealthy"
+
+	// ForcedErrorCode is set when the status of the probe is forced with an Override.
+	ForcedErrorCode ErrorCode = "forced"
+
+	// ConfigurationErrorCode is set when the probe is misconfigured (e.g. a nil database).
+	ConfigurationErrorCode ErrorCode = "invalid_configuration"
+)
+
+// CheckError is a structured error of a ProbeCheckFn.
+// It is returned by the predefined checks, and it can be returned by the custom ones.
+//
+// errors.Is(err, ErrCheckFailed) is true for a CheckError.
+type CheckError struct {
+	Code ErrorCode
+
+	// Message is a human-readable description of the failure.
+	Message string
+
+	// Observed and Threshold are the value that was checked and its limit (e.g. the HTTP status code, or a latency).
+	Observed  interface{}
+	Threshold interface{}
+
+	// Retryable is set if the failure is likely transient.
+	Retryable bool
+
+	// Err is the cause of the failure.
+	Err error
+}
+
+func (e *CheckError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = ErrCheckFailed.Error()
+	}
+
+	if e.Observed != nil {
+		if e.Threshold != nil {
+			message = fmt.Sprintf("%s (observed: %v, threshold: %v)", message, e.Observed, e.Threshold)
+		} else {
+			message = fmt.Sprintf("%s (observed: %v)", message, e.Observed)
+		}
+	}
+
+	if e.Err != nil {
+		message = fmt.Sprintf("%s: %s", message, e.Err)
+	}
+
+	return message
+}
+
+func (e *CheckError) Unwrap() error { return e.Err }
+
+func (e *CheckError) Is(target error) bool { return target == ErrCheckFailed }
+
+// NewCheckError creates a CheckError with the cause of the failure.
+//
+// The code is replaced by TimeoutErrorCode, and the error is retryable, if the cause is a timeout.
+func NewCheckError(code ErrorCode, message string, err error) *CheckError {
+	e := &CheckError{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+
+	if isTimeout(err) {
+		e.Code = TimeoutErrorCode
+		e.Retryable = true
+	}
+
+	return e
+}
+
+// ErrorCodeOf returns the code of the CheckError in the chain of err.
+// For the other errors, it returns TimeoutErrorCode for the timeouts, and UnknownErrorCode otherwise.
+//
+// It returns an empty code if err is nil.
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	var checkErr *CheckError
+	if errors.As(err, &checkErr) && checkErr.Code != "" {
+		return checkErr.Code
+	}
+
+	if isTimeout(err) {
+		return TimeoutErrorCode
+	}
+
+	return UnknownErrorCode
+}
+
+func isTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
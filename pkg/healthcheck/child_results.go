@@ -0,0 +1,44 @@
+package healthcheck
+
+import (
+	"context"
+	"sync"
+)
+
+type childResultsKeyType string
+
+const childResultsKey = childResultsKeyType("childResults")
+
+type childResultsRecorder struct {
+	mu      sync.Mutex
+	results []ExecutionResult
+}
+
+// ReportChildResults attaches nested results to the ExecutionResult of the probe being executed.
+//
+// It is meant to be called from a ProbeCheckFn that aggregates other checks,
+// e.g. the probes of a remote health check instance.
+func ReportChildResults(ctx context.Context, children ...ExecutionResult) {
+	recorder, ok := ctx.Value(childResultsKey).(*childResultsRecorder)
+	if !ok {
+		return
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	recorder.results = append(recorder.results, children...)
+}
+
+func (r *childResultsRecorder) list() []ExecutionResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.results
+}
+
+func withChildResultsRecorder(ctx context.Context) (context.Context, *childResultsRecorder) {
+	recorder := &childResultsRecorder{}
+
+	return context.WithValue(ctx, childResultsKey, recorder), recorder
+}
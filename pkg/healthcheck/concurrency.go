@@ -0,0 +1,105 @@
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type ConcurrencyOptions struct {
+	// MaxConcurrency is the maximum number of probes executed at the same time, by all the callers.
+	// It is unlimited if it is not positive.
+	MaxConcurrency int
+
+	// MinInterval is the minimum interval between two executions of a probe.
+	// Within the interval, the last result of the probe is returned instead (see ExecutionResult.Cached).
+	// It can be set for each probe with Probe.MinInterval, and a negative Probe.MinInterval opts the probe out
+	// (as for the draining and maintenance probes, whose state changes must be reported immediately).
+	MinInterval time.Duration
+
+	// Coalesce makes the concurrent callers of ExecuteAllProbes, or of ExecuteProbesByKind with the same kind,
+	// share a single execution.
+	// The shared execution is not cancelled with the context of a caller: a cancelled caller stops waiting,
+	// while the execution goes on for the other ones, for up to the CoalesceTimeout.
+	Coalesce bool
+
+	// CoalesceTimeout is the maximum duration of a shared execution. By default, it is 30 seconds.
+	CoalesceTimeout time.Duration
+}
+
+type flightCall struct {
+	done    chan struct{}
+	results []ExecutionResult
+	err     error
+}
+
+// flightGroup makes the concurrent calls with the same key share a single execution.
+type flightGroup struct {
+	mu      sync.Mutex
+	calls   map[string]*flightCall
+	timeout time.Duration
+}
+
+// do executes fn, or waits for the execution in progress with the same key.
+//
+// The execution uses a context detached from the one of the caller, with the timeout of the flightGroup.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(ctx context.Context) ([]ExecutionResult, error)) ([]ExecutionResult, error) {
+	g.mu.Lock()
+	c, ok := g.calls[key]
+	if !ok {
+		c = &flightCall{
+			done: make(chan struct{}),
+		}
+		g.calls[key] = c
+
+		go g.execute(detachedContext{parent: ctx}, key, c, fn)
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return append([]ExecutionResult(nil), c.results...), c.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) execute(ctx context.Context, key string, c *flightCall, fn func(ctx context.Context) ([]ExecutionResult, error)) {
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
+
+	c.results, c.err = fn(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, key)
+	g.mu.Unlock()
+
+	close(c.done)
+}
+
+// detachedContext keeps the values of its parent (e.g. the trace span), but not its deadline and cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+
+func (detachedContext) Done() <-chan struct{} { return nil }
+
+func (detachedContext) Err() error { return nil }
+
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func newFlightGroup(timeout time.Duration) *flightGroup {
+	if timeout <= 0 {
+		const defaultTimeout = 30 * time.Second
+		timeout = defaultTimeout
+	}
+
+	g := &flightGroup{
+		calls:   map[string]*flightCall{},
+		timeout: timeout,
+	}
+
+	return g
+}
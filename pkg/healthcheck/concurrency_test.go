@@ -0,0 +1,303 @@
+package healthcheck
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// waitingContext signals when a caller waits for it to be done,
+// i.e. when the caller joined a shared execution.
+type waitingContext struct {
+	context.Context
+	waiting chan<- struct{}
+}
+
+func (c waitingContext) Done() <-chan struct{} {
+	c.waiting <- struct{}{}
+
+	return c.Context.Done()
+}
+
+func TestServiceExecutesTheProbeOncePerMinInterval(t *testing.T) {
+	tests := []struct {
+		name               string
+		serviceMinInterval time.Duration
+		probeMinInterval   time.Duration
+		expectedExecutions int32
+	}{
+		{
+			name:               "no minimum interval",
+			expectedExecutions: 5,
+		},
+		{
+			name:               "minimum interval of the service",
+			serviceMinInterval: time.Minute,
+			expectedExecutions: 1,
+		},
+		{
+			name:               "minimum interval of the probe",
+			probeMinInterval:   time.Minute,
+			expectedExecutions: 1,
+		},
+		{
+			name:               "probe opted out",
+			serviceMinInterval: time.Minute,
+			probeMinInterval:   -1,
+			expectedExecutions: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var executions int32
+			probeStore := NewInMemoryProbeStore()
+			err := probeStore.Add(Probe{
+				Name:        "db",
+				Kind:        ReadinessProbeKind,
+				MinInterval: tt.probeMinInterval,
+				CheckFn: func(context.Context) error {
+					atomic.AddInt32(&executions, 1)
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := NewService(probeStore, NewNoOpMetricsService(), WithConcurrency(ConcurrencyOptions{
+				MinInterval: tt.serviceMinInterval,
+			}))
+
+			for i := 0; i < 5; i++ {
+				executionResults, err := service.ExecuteAllProbes(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				cached := executionResults[0].Cached
+				if i == 0 && cached {
+					t.Fatal("expected the first result not to be cached")
+				}
+
+				if executionResults[0].Probe.Health != HealthyStatus {
+					t.Fatalf("expected a healthy result, got %q", executionResults[0].Probe.Health)
+				}
+			}
+
+			if executions != tt.expectedExecutions {
+				t.Fatalf("expected %d executions, got %d", tt.expectedExecutions, executions)
+			}
+		})
+	}
+}
+
+func TestServiceCoalescesTheConcurrentExecutions(t *testing.T) {
+	const callers = 5
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, service Service) ([]ExecutionResult, error)
+	}{
+		{
+			name: "all probes",
+			run: func(ctx context.Context, service Service) ([]ExecutionResult, error) {
+				return service.ExecuteAllProbes(ctx)
+			},
+		},
+		{
+			name: "probes of a kind",
+			run: func(ctx context.Context, service Service) ([]ExecutionResult, error) {
+				return service.ExecuteProbesByKind(ctx, ReadinessProbeKind)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var executions int32
+			started := make(chan struct{})
+			release := make(chan struct{})
+
+			probeStore := NewInMemoryProbeStore()
+			err := probeStore.Add(Probe{
+				Name: "db",
+				Kind: ReadinessProbeKind,
+				CheckFn: func(context.Context) error {
+					if atomic.AddInt32(&executions, 1) == 1 {
+						close(started)
+					}
+					<-release
+					return nil
+				},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			service := NewService(probeStore, NewNoOpMetricsService(), WithConcurrency(ConcurrencyOptions{Coalesce: true}))
+
+			var wg sync.WaitGroup
+			waiting := make(chan struct{}, callers)
+			results := make([][]ExecutionResult, callers)
+			errs := make([]error, callers)
+
+			wg.Add(callers)
+			for i := 0; i < callers; i++ {
+				go func(i int) {
+					defer wg.Done()
+
+					ctx := waitingContext{Context: context.Background(), waiting: waiting}
+					results[i], errs[i] = tt.run(ctx, service)
+				}(i)
+
+				if i == 0 {
+					<-started
+				}
+			}
+
+			for i := 0; i < callers; i++ {
+				<-waiting
+			}
+			close(release)
+			wg.Wait()
+
+			if executions != 1 {
+				t.Fatalf("expected a single execution, got %d", executions)
+			}
+
+			for i := 0; i < callers; i++ {
+				if errs[i] != nil {
+					t.Fatal(errs[i])
+				}
+
+				if len(results[i]) != 1 || results[i][0].Probe.Health != HealthyStatus {
+					t.Fatalf("expected the healthy result of the shared execution, got %v", results[i])
+				}
+			}
+		})
+	}
+}
+
+func TestServiceCoalescedExecutionOutlivesACancelledCaller(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	probeStore := NewInMemoryProbeStore()
+	err := probeStore.Add(Probe{
+		Name: "db",
+		Kind: ReadinessProbeKind,
+		CheckFn: func(ctx context.Context) error {
+			close(started)
+			<-release
+			return ctx.Err()
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(probeStore, NewNoOpMetricsService(), WithConcurrency(ConcurrencyOptions{Coalesce: true}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := service.ExecuteAllProbes(ctx)
+		cancelled <- err
+	}()
+	<-started
+
+	waiting := make(chan struct{}, 1)
+	type outcome struct {
+		results []ExecutionResult
+		err     error
+	}
+	other := make(chan outcome, 1)
+	go func() {
+		results, err := service.ExecuteAllProbes(waitingContext{Context: context.Background(), waiting: waiting})
+		other <- outcome{results: results, err: err}
+	}()
+	<-waiting
+
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("expected the cancelled caller to stop waiting, got %v", err)
+	}
+
+	close(release)
+	o := <-other
+	if o.err != nil {
+		t.Fatal(o.err)
+	}
+
+	if len(o.results) != 1 || o.results[0].Probe.Health != HealthyStatus {
+		t.Fatalf("expected the shared execution not to be cancelled, got %v", o.results)
+	}
+}
+
+func TestServiceLimitsTheConcurrentExecutions(t *testing.T) {
+	const maxConcurrency = 2
+
+	var running, maxRunning int32
+	started := make(chan struct{}, 5)
+	release := make(chan struct{})
+
+	probeStore := NewInMemoryProbeStore()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		err := probeStore.Add(Probe{
+			Name: name,
+			Kind: ReadinessProbeKind,
+			CheckFn: func(context.Context) error {
+				n := atomic.AddInt32(&running, 1)
+				defer atomic.AddInt32(&running, -1)
+
+				for {
+					m := atomic.LoadInt32(&maxRunning)
+					if n <= m || atomic.CompareAndSwapInt32(&maxRunning, m, n) {
+						break
+					}
+				}
+
+				started <- struct{}{}
+				<-release
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	service := NewService(probeStore, NewNoOpMetricsService(), WithConcurrency(ConcurrencyOptions{MaxConcurrency: maxConcurrency}))
+
+	done := make(chan []ExecutionResult, 1)
+	go func() {
+		executionResults, _ := service.ExecuteAllProbes(context.Background())
+		done <- executionResults
+	}()
+
+	for i := 0; i < maxConcurrency; i++ {
+		<-started
+	}
+
+	select {
+	case <-started:
+		t.Fatalf("expected at most %d probes to be executed at the same time", maxConcurrency)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	executionResults := <-done
+
+	if maxRunning != maxConcurrency {
+		t.Fatalf("expected %d probes to be executed at the same time, got %d", maxConcurrency, maxRunning)
+	}
+
+	for _, r := range executionResults {
+		if r.Probe.Health != HealthyStatus {
+			t.Fatalf("expected %q to be healthy, got %q", r.Probe.Name, r.Probe.Health)
+		}
+	}
+}
@@ -23,6 +23,18 @@ const (
 	HealthName     = "health"
 	HealthEndpoint = "/health"
 
+	HistoryName     = "history"
+	HistoryEndpoint = "/health/history"
+
+	DashboardName     = "dashboard"
+	DashboardEndpoint = "/health/dashboard"
+
+	OverridesName     = "overrides"
+	OverridesEndpoint = "/health/overrides"
+
+	FederatedName     = "federated"
+	FederatedEndpoint = "/health/federated"
+
 	MetricsName     = "metrics"
 	MetricsEndpoint = "/metrics"
 )
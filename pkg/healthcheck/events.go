@@ -0,0 +1,243 @@
+package healthcheck
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type EventType string
+
+const (
+	ProbeAddedEvent   EventType = "probe_added"
+	ProbeRemovedEvent EventType = "probe_removed"
+
+	// ProbeStatusChangedEvent is emitted when the status of a probe changes,
+	// including when the probe is executed for the first time (the previous status is empty).
+	ProbeStatusChangedEvent EventType = "probe_status_changed"
+
+	// KindStatusChangedEvent is emitted when the overall status of a ProbeKind changes,
+	// i.e. when its first probe becomes unhealthy, or when its last unhealthy probe becomes healthy.
+	KindStatusChangedEvent EventType = "kind_status_changed"
+)
+
+type Event struct {
+	Type EventType
+	Time time.Time
+	Kind ProbeKind
+
+	// Probe is set for all the events, except for KindStatusChangedEvent.
+	Probe Probe
+
+	// PreviousStatus and Status are set for the status change events.
+	PreviousStatus ProbeHealthStatus
+	Status         ProbeHealthStatus
+
+	// Err is the error of the execution that changed the status of the probe.
+	Err error
+}
+
+// Subscription receives the events of the Service.
+//
+// The events are delivered without blocking the probe execution:
+// if the buffer of the subscription is full, the events are dropped.
+type Subscription interface {
+	Events() <-chan Event
+
+	// Dropped returns the number of events that were dropped because the buffer was full.
+	Dropped() uint64
+
+	// Unsubscribe stops the delivery of the events and closes the channel.
+	Unsubscribe()
+}
+
+type subscription struct {
+	broker  *eventBroker
+	c       chan Event
+	dropped uint64
+	once    sync.Once
+}
+
+func (s *subscription) Events() <-chan Event { return s.c }
+
+func (s *subscription) Dropped() uint64 { return atomic.LoadUint64(&s.dropped) }
+
+func (s *subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.broker.remove(s)
+	})
+}
+
+type eventBroker struct {
+	mu          sync.RWMutex
+	subscribers map[*subscription]struct{}
+}
+
+func (b *eventBroker) subscribe(bufferSize int) *subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+
+	s := &subscription{
+		broker: b,
+		c:      make(chan Event, bufferSize),
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+func (b *eventBroker) remove(s *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, s)
+	close(s.c)
+}
+
+func (b *eventBroker) publish(events ...Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, e := range events {
+		for s := range b.subscribers {
+			select {
+			case s.c <- e:
+			default:
+				atomic.AddUint64(&s.dropped, 1)
+			}
+		}
+	}
+}
+
+func newEventBroker() *eventBroker {
+	b := &eventBroker{
+		subscribers: map[*subscription]struct{}{},
+	}
+
+	return b
+}
+
+type probeStatus struct {
+	kind        ProbeKind
+	health      ProbeHealthStatus
+	isUnhealthy bool
+}
+
+// statusTracker keeps the last status of the probes and of their kinds, to detect the status transitions.
+type statusTracker struct {
+	mu sync.Mutex
+
+	probes map[string]probeStatus
+	kinds  map[ProbeKind]ProbeHealthStatus
+}
+
+// update records the results and returns the status change events.
+func (t *statusTracker) update(executionResults []ExecutionResult) []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	events := make([]Event, 0)
+	kinds := map[ProbeKind]struct{}{}
+
+	for _, r := range executionResults {
+		p := r.Probe
+		previous := t.probes[p.Name]
+		t.probes[p.Name] = probeStatus{
+			kind:        p.Kind,
+			health:      p.Health,
+			isUnhealthy: r.IsUnhealthy(),
+		}
+		kinds[p.Kind] = struct{}{}
+
+		if previous.health == p.Health {
+			continue
+		}
+
+		events = append(events, Event{
+			Type:           ProbeStatusChangedEvent,
+			Time:           now,
+			Kind:           p.Kind,
+			Probe:          p,
+			PreviousStatus: previous.health,
+			Status:         p.Health,
+			Err:            r.Err,
+		})
+	}
+
+	for kind := range kinds {
+		events = append(events, t.updateKind(kind, now)...)
+	}
+
+	return events
+}
+
+// remove forgets the probes and returns the resulting kind status change events.
+func (t *statusTracker) remove(probes ...Probe) []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	events := make([]Event, 0)
+
+	for _, p := range probes {
+		status, ok := t.probes[p.Name]
+		if !ok {
+			continue
+		}
+
+		delete(t.probes, p.Name)
+		events = append(events, t.updateKind(status.kind, now)...)
+	}
+
+	return events
+}
+
+func (t *statusTracker) updateKind(kind ProbeKind, now time.Time) []Event {
+	var isTracked bool
+	health := HealthyStatus
+	for _, status := range t.probes {
+		if status.kind != kind {
+			continue
+		}
+
+		isTracked = true
+		if status.isUnhealthy {
+			health = UnhealthyStatus
+		}
+	}
+
+	if !isTracked {
+		delete(t.kinds, kind)
+		return nil
+	}
+
+	previous := t.kinds[kind]
+	t.kinds[kind] = health
+	if previous == health {
+		return nil
+	}
+
+	e := Event{
+		Type:           KindStatusChangedEvent,
+		Time:           now,
+		Kind:           kind,
+		PreviousStatus: previous,
+		Status:         health,
+	}
+
+	return []Event{e}
+}
+
+func newStatusTracker() *statusTracker {
+	t := &statusTracker{
+		probes: map[string]probeStatus{},
+		kinds:  map[ProbeKind]ProbeHealthStatus{},
+	}
+
+	return t
+}
@@ -1,6 +1,45 @@
 package healthcheck
 
+import "time"
+
 type ExecutionResult struct {
 	Probe Probe
 	Err   error
+
+	StartedAt time.Time
+	Duration  time.Duration
+
+	// Flapping is set if the probe changes its status too often (see FlappingOptions).
+	Flapping bool
+
+	// Children are the nested results reported by the probe (see ReportChildResults).
+	Children []ExecutionResult
+
+	// Cached is set if the result is the one of a previous execution (see ConcurrencyOptions.MinInterval).
+	Cached bool
+
+	// Override is set if the probe has an override (see Service.SetOverride).
+	Override *Override
+}
+
+// IsUnhealthy reports if the result makes the overall status unhealthy,
+// i.e. the probe is unhealthy and it is not muted.
+func (r ExecutionResult) IsUnhealthy() bool {
+	if r.Probe.Health != UnhealthyStatus {
+		return false
+	}
+
+	return r.Override == nil || r.Override.Mode != MutedOverride
+}
+
+// Executed reports if the ProbeCheckFn was executed for this result,
+// i.e. the result is not cached, and the probe is not disabled or forced (see Service.SetOverride).
+//
+// The MetricsService(s) only count the executed results, and the other ones only update the status of the probe.
+func (r ExecutionResult) Executed() bool {
+	if r.Cached || r.StartedAt.IsZero() {
+		return false
+	}
+
+	return r.Override == nil || r.Override.Mode == MutedOverride
 }
@@ -0,0 +1,172 @@
+// Package expvarmetrics publishes the metrics of the probes in expvar.
+//
+// It is a separate package, so the healthcheck package itself doesn't import expvar,
+// which registers the '/debug/vars' handler in the http.DefaultServeMux.
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+)
+
+// ProbeMetrics are the metrics of a probe, published by the expvar MetricsService.
+type ProbeMetrics struct {
+	Kind   healthcheck.ProbeKind         `json:"kind"`
+	Status healthcheck.ProbeHealthStatus `json:"status"`
+
+	Executions uint64                           `json:"executions"`
+	Failures   uint64                           `json:"failures"`
+	ErrorCodes map[healthcheck.ErrorCode]uint64 `json:"error_codes,omitempty"`
+
+	LastDurationMs  float64   `json:"last_duration_ms"`
+	TotalDurationMs float64   `json:"total_duration_ms"`
+	LastExecutedAt  time.Time `json:"last_executed_at"`
+}
+
+// publishedMetrics has the metrics of the probes, published in expvar with a name.
+type publishedMetrics struct {
+	mu     sync.RWMutex
+	probes map[string]ProbeMetrics
+}
+
+func (m *publishedMetrics) update(executionResults []healthcheck.ExecutionResult) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range executionResults {
+		p := e.Probe
+		if p.Health != healthcheck.HealthyStatus && p.Health != healthcheck.UnhealthyStatus {
+			continue
+		}
+
+		pm := m.probes[p.Name]
+		pm.Kind = p.Kind
+		pm.Status = p.Health
+
+		// the results that were not executed only update the status
+		if e.Executed() {
+			durationMs := float64(e.Duration.Microseconds()) / 1000
+
+			pm.Executions++
+			pm.LastDurationMs = durationMs
+			pm.TotalDurationMs += durationMs
+			pm.LastExecutedAt = e.StartedAt
+
+			if e.Err != nil {
+				pm.Failures++
+
+				codes := make(map[healthcheck.ErrorCode]uint64, len(pm.ErrorCodes)+1)
+				for code, count := range pm.ErrorCodes {
+					codes[code] = count
+				}
+				codes[healthcheck.ErrorCodeOf(e.Err)]++
+				pm.ErrorCodes = codes
+			}
+		}
+
+		m.probes[p.Name] = pm
+	}
+}
+
+func (m *publishedMetrics) delete(names ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, name := range names {
+		delete(m.probes, name)
+	}
+}
+
+func (m *publishedMetrics) snapshot() map[string]ProbeMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	probes := make(map[string]ProbeMetrics, len(m.probes))
+	for name, pm := range m.probes {
+		probes[name] = pm
+	}
+
+	return probes
+}
+
+var (
+	publishedMu sync.Mutex
+
+	// published has the metrics published by name, since expvar can't publish a name twice.
+	published = map[string]*publishedMetrics{}
+)
+
+type metricsService struct {
+	metrics *publishedMetrics
+}
+
+// GetHandler returns a handler that responds with the metrics of the probes, as a JSON map of ProbeMetrics by probe name.
+func (s metricsService) GetHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(s.metrics.snapshot())
+		if err != nil {
+			log.Printf("Error: %s\n", err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		_, err = w.Write(body)
+		if err != nil {
+			log.Println(err)
+		}
+	})
+}
+
+func (s metricsService) UpdateGauge(executionResults ...healthcheck.ExecutionResult) {
+	s.metrics.update(executionResults)
+}
+
+func (s metricsService) DeleteProbeMetrics(probes ...healthcheck.Probe) {
+	names := make([]string, 0, len(probes))
+	for _, p := range probes {
+		names = append(names, p.Name)
+	}
+
+	s.metrics.delete(names...)
+}
+
+// NewMetricsService publishes the metrics of the probes in expvar with this name (e.g. "healthcheck"),
+// so they are served at '/debug/vars' with the other expvar variables.
+// The metrics are a map of ProbeMetrics by probe name.
+//
+// The MetricsService(s) with the same name share the same metrics.
+// If the name is already published by another package, the metrics are only served by GetHandler.
+func NewMetricsService(name string) healthcheck.MetricsService {
+	publishedMu.Lock()
+	defer publishedMu.Unlock()
+
+	m, ok := published[name]
+	if !ok {
+		m = &publishedMetrics{
+			probes: map[string]ProbeMetrics{},
+		}
+
+		if expvar.Get(name) == nil {
+			expvar.Publish(name, expvar.Func(func() interface{} {
+				return m.snapshot()
+			}))
+		} else {
+			log.Printf("could not publish the metrics in expvar: %q is already published\n", name)
+		}
+		published[name] = m
+	}
+
+	s := &metricsService{
+		metrics: m,
+	}
+
+	return s
+}
@@ -0,0 +1,85 @@
+package expvarmetrics
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+)
+
+func TestMetricsServiceCountsTheExecutedResults(t *testing.T) {
+	failed := func(o *healthcheck.Override) healthcheck.ExecutionResult {
+		return healthcheck.ExecutionResult{
+			Probe:     healthcheck.Probe{Name: "db", Kind: healthcheck.ReadinessProbeKind, Health: healthcheck.UnhealthyStatus},
+			Err:       &healthcheck.CheckError{Code: healthcheck.ConnectionErrorCode, Message: "could not connect"},
+			StartedAt: time.Now(),
+			Duration:  2 * time.Millisecond,
+			Override:  o,
+		}
+	}
+
+	tests := []struct {
+		name               string
+		result             func() healthcheck.ExecutionResult
+		expectedExecutions uint64
+		expectedStatus     healthcheck.ProbeHealthStatus
+	}{
+		{
+			name:               "executed",
+			result:             func() healthcheck.ExecutionResult { return failed(nil) },
+			expectedExecutions: 1,
+			expectedStatus:     healthcheck.UnhealthyStatus,
+		},
+		{
+			name: "muted",
+			result: func() healthcheck.ExecutionResult {
+				return failed(&healthcheck.Override{Mode: healthcheck.MutedOverride})
+			},
+			expectedExecutions: 1,
+			expectedStatus:     healthcheck.UnhealthyStatus,
+		},
+		{
+			name: "cached",
+			result: func() healthcheck.ExecutionResult {
+				r := failed(nil)
+				r.Cached = true
+				return r
+			},
+			expectedStatus: healthcheck.UnhealthyStatus,
+		},
+		{
+			name: "forced",
+			result: func() healthcheck.ExecutionResult {
+				return failed(&healthcheck.Override{Mode: healthcheck.ForcedOverride, Status: healthcheck.UnhealthyStatus})
+			},
+			expectedStatus: healthcheck.UnhealthyStatus,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewMetricsService("healthcheck_test_" + tt.name)
+			s.UpdateGauge(tt.result())
+
+			rec := httptest.NewRecorder()
+			s.GetHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+			var probes map[string]ProbeMetrics
+			err := json.Unmarshal(rec.Body.Bytes(), &probes)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pm := probes["db"]
+			if pm.Status != tt.expectedStatus {
+				t.Fatalf("expected the status %q, got %q", tt.expectedStatus, pm.Status)
+			}
+
+			if pm.Executions != tt.expectedExecutions || pm.Failures != tt.expectedExecutions {
+				t.Fatalf("expected %d executions and failures, got %+v", tt.expectedExecutions, pm)
+			}
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package healthcheck
+
+import "sync"
+
+// FlappingOptions configures the flap detection, which is similar to the one of Nagios:
+// the state change rate is computed over the last WindowSize results of a probe,
+// with the recent state changes weighing more than the old ones.
+//
+// A probe starts flapping when the rate is above the HighThreshold,
+// and stops flapping when the rate drops below the LowThreshold.
+type FlappingOptions struct {
+	// WindowSize is the number of results used to compute the state change rate.
+	// The rate is only computed once the window is full, so a probe can't be flapping before.
+	// The flap detection is disabled if it is less than 3.
+	WindowSize int
+
+	HighThreshold float64
+	LowThreshold  float64
+
+	// HoldStatus keeps reporting the status (and the error) the probe had before it started flapping,
+	// until it stops flapping.
+	HoldStatus bool
+}
+
+func DefaultFlappingOptions() FlappingOptions {
+	return FlappingOptions{
+		WindowSize:    21,
+		HighThreshold: 0.5,
+		LowThreshold:  0.25,
+	}
+}
+
+type flapState struct {
+	statuses     []ProbeHealthStatus
+	isFlapping   bool
+	stableStatus ProbeHealthStatus
+	stableErr    error
+}
+
+type flapDetector struct {
+	mu sync.Mutex
+
+	opts   FlappingOptions
+	states map[string]*flapState
+}
+
+// apply records the results, and marks the ones of the flapping probes.
+// It returns whether a probe started or stopped flapping.
+func (d *flapDetector) apply(executionResults []ExecutionResult) bool {
+	if d.opts.WindowSize < 3 {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var hasChanged bool
+	for i := range executionResults {
+		r := &executionResults[i]
+		if r.Cached || (r.Override != nil && r.Override.Mode != MutedOverride) {
+			continue
+		}
+
+		state, ok := d.states[r.Probe.Name]
+		if !ok {
+			state = &flapState{}
+			d.states[r.Probe.Name] = state
+		}
+
+		state.statuses = append(state.statuses, r.Probe.Health)
+		if len(state.statuses) > d.opts.WindowSize {
+			state.statuses = state.statuses[1:]
+		}
+
+		// a few state changes in a partial window (e.g. the start of an outage) are not a flap
+		if len(state.statuses) == d.opts.WindowSize {
+			rate := stateChangeRate(state.statuses)
+			switch {
+			case !state.isFlapping && rate > d.opts.HighThreshold:
+				state.isFlapping = true
+				hasChanged = true
+			case state.isFlapping && rate < d.opts.LowThreshold:
+				state.isFlapping = false
+				hasChanged = true
+			}
+		}
+
+		if !state.isFlapping {
+			state.stableStatus = r.Probe.Health
+			state.stableErr = r.Err
+		}
+
+		r.Flapping = state.isFlapping
+		if r.Flapping && d.opts.HoldStatus && state.stableStatus != "" {
+			holdStatus(r, state)
+		}
+	}
+
+	return hasChanged
+}
+
+// holdStatus replaces the result of a flapping probe with its stable status, keeping the error consistent with it.
+func holdStatus(r *ExecutionResult, state *flapState) {
+	r.Probe.Health = state.stableStatus
+
+	switch {
+	case state.stableStatus == HealthyStatus:
+		r.Err = nil
+	case state.stableErr != nil:
+		r.Err = state.stableErr
+	case r.Err == nil:
+		// the stable error is unknown, e.g. when the state was restored after a restart
+		r.Err = ErrCheckFailed
+	}
+}
+
+func (d *flapDetector) delete(names ...string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, name := range names {
+		delete(d.states, name)
+	}
+}
+
+// windows returns the statuses used for the flap detection, by probe name.
+func (d *flapDetector) windows() map[string][]ProbeHealthStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	windows := make(map[string][]ProbeHealthStatus, len(d.states))
+	for name, state := range d.states {
+		windows[name] = append([]ProbeHealthStatus(nil), state.statuses...)
+	}
+
+	return windows
+}
+
+// restore replaces the flap detection state of the probe, e.g. with the one persisted before a restart.
+func (d *flapDetector) restore(name string, statuses []ProbeHealthStatus, isFlapping bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(statuses) > d.opts.WindowSize {
+		statuses = statuses[len(statuses)-d.opts.WindowSize:]
+	}
+
+	state := &flapState{
+		statuses:   append([]ProbeHealthStatus(nil), statuses...),
+		isFlapping: isFlapping,
+	}
+
+	if !isFlapping && len(statuses) > 0 {
+		state.stableStatus = statuses[len(statuses)-1]
+	}
+
+	d.states[name] = state
+}
+
+// stateChangeRate returns the weighted rate of state changes, between 0 and 1.
+// The weights increase linearly from 0.8 for the oldest change to 1.2 for the newest one.
+func stateChangeRate(statuses []ProbeHealthStatus) float64 {
+	transitions := len(statuses) - 1
+	if transitions < 1 {
+		return 0
+	}
+
+	var rate float64
+	for i := 1; i < len(statuses); i++ {
+		if statuses[i] == statuses[i-1] {
+			continue
+		}
+
+		weight := 1.0
+		if transitions > 1 {
+			weight = 0.8 + 0.4*float64(i-1)/float64(transitions-1)
+		}
+
+		rate += weight
+	}
+
+	return rate / float64(transitions)
+}
+
+func newFlapDetector(opts FlappingOptions) *flapDetector {
+	d := &flapDetector{
+		opts:   opts,
+		states: map[string]*flapState{},
+	}
+
+	return d
+}
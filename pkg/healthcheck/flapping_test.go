@@ -0,0 +1,79 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFlapDetector(t *testing.T) {
+	const (
+		h = HealthyStatus
+		u = UnhealthyStatus
+	)
+
+	tests := []struct {
+		name     string
+		statuses []ProbeHealthStatus
+		flapping []bool
+		reported []ProbeHealthStatus
+	}{
+		{
+			name:     "single transition",
+			statuses: []ProbeHealthStatus{h, u, u, u, u, u},
+			flapping: []bool{false, false, false, false, false, false},
+			reported: []ProbeHealthStatus{h, u, u, u, u, u},
+		},
+		{
+			name:     "transitions in a partial window",
+			statuses: []ProbeHealthStatus{h, u, h, u},
+			flapping: []bool{false, false, false, false},
+			reported: []ProbeHealthStatus{h, u, h, u},
+		},
+		{
+			name:     "flap",
+			statuses: []ProbeHealthStatus{h, h, h, h, h, u, h, u, h},
+			flapping: []bool{false, false, false, false, false, false, true, true, true},
+			reported: []ProbeHealthStatus{h, h, h, h, h, u, u, u, u},
+		},
+		{
+			// the probe stops flapping only when the rate drops below the low threshold
+			name:     "recovery with hysteresis",
+			statuses: []ProbeHealthStatus{h, h, h, h, h, u, h, u, h, h, h, h, h},
+			flapping: []bool{false, false, false, false, false, false, true, true, true, true, true, false, false},
+			reported: []ProbeHealthStatus{h, h, h, h, h, u, u, u, u, u, u, h, h},
+		},
+	}
+
+	opts := FlappingOptions{
+		WindowSize:    5,
+		HighThreshold: 0.5,
+		LowThreshold:  0.25,
+		HoldStatus:    true,
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFlapDetector(opts)
+
+			for i, status := range tt.statuses {
+				r := ExecutionResult{Probe: Probe{Name: "db", Health: status}}
+				if status == u {
+					r.Err = errors.New("connection refused")
+				}
+
+				executionResults := []ExecutionResult{r}
+				d.apply(executionResults)
+				r = executionResults[0]
+
+				if r.Flapping != tt.flapping[i] || r.Probe.Health != tt.reported[i] {
+					t.Fatalf("result %d: expected flapping=%t and %s, got flapping=%t and %s",
+						i, tt.flapping[i], tt.reported[i], r.Flapping, r.Probe.Health)
+				}
+
+				if (r.Probe.Health == u) != (r.Err != nil) {
+					t.Fatalf("result %d: expected the error to match the %s status, got %v", i, r.Probe.Health, r.Err)
+				}
+			}
+		})
+	}
+}
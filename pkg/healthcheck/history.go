@@ -0,0 +1,227 @@
+package healthcheck
+
+import (
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// HistoryEntry is the compact form of an ExecutionResult kept in the history of a probe.
+type HistoryEntry struct {
+	Time   time.Time         `json:"time"`
+	Status ProbeHealthStatus `json:"status"`
+
+	// Duration is in nanoseconds when encoded as JSON.
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+type HistoryOptions struct {
+	// Depth is the number of results kept for each probe.
+	// The history is disabled if it is not positive.
+	Depth int
+
+	// MaxProbes is the maximum number of probes for which a history is kept.
+	// When it is exceeded, the history of the least recently executed probe is dropped.
+	MaxProbes int
+
+	// MaxErrorLength is the maximum length of the error messages kept in the history.
+	MaxErrorLength int
+}
+
+func DefaultHistoryOptions() HistoryOptions {
+	return HistoryOptions{
+		Depth:          10,
+		MaxProbes:      1000,
+		MaxErrorLength: 256,
+	}
+}
+
+// newHistoryEntry truncates the error message to maxErrorLength, if it is positive.
+func newHistoryEntry(executionResult ExecutionResult, maxErrorLength int) HistoryEntry {
+	e := HistoryEntry{
+		Time:     executionResult.StartedAt,
+		Status:   executionResult.Probe.Health,
+		Duration: executionResult.Duration,
+	}
+
+	if executionResult.Err != nil {
+		e.Error = executionResult.Err.Error()
+		if maxErrorLength > 0 && len(e.Error) > maxErrorLength {
+			e.Error = truncateUTF8(e.Error, maxErrorLength)
+		}
+	}
+
+	return e
+}
+
+// truncateUTF8 truncates the string to at most n bytes, without splitting a character.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// ringBuffer keeps the last len(entries) entries.
+type ringBuffer struct {
+	entries  []HistoryEntry
+	next     int
+	isFull   bool
+	lastSeen time.Time
+}
+
+func (b *ringBuffer) add(e HistoryEntry) {
+	b.entries[b.next] = e
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.isFull = true
+	}
+}
+
+// list returns the entries from the oldest to the newest.
+func (b *ringBuffer) list() []HistoryEntry {
+	if !b.isFull {
+		return append([]HistoryEntry(nil), b.entries[:b.next]...)
+	}
+
+	l := make([]HistoryEntry, 0, len(b.entries))
+	l = append(l, b.entries[b.next:]...)
+	l = append(l, b.entries[:b.next]...)
+
+	return l
+}
+
+type resultHistory struct {
+	mu sync.RWMutex
+
+	opts    HistoryOptions
+	buffers map[string]*ringBuffer
+}
+
+func (h *resultHistory) record(executionResults ...ExecutionResult) {
+	if h.opts.Depth <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, executionResult := range executionResults {
+		if executionResult.Cached {
+			continue
+		}
+
+		name := executionResult.Probe.Name
+
+		b, ok := h.buffers[name]
+		if !ok {
+			h.evict()
+
+			b = &ringBuffer{
+				entries: make([]HistoryEntry, h.opts.Depth),
+			}
+			h.buffers[name] = b
+		}
+
+		b.add(newHistoryEntry(executionResult, h.opts.MaxErrorLength))
+		b.lastSeen = time.Now()
+	}
+}
+
+// restore replaces the history of the probe, e.g. with the one persisted before a restart.
+func (h *resultHistory) restore(name string, entries []HistoryEntry) {
+	if h.opts.Depth <= 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.buffers[name]; !ok {
+		h.evict()
+	}
+
+	b := &ringBuffer{
+		entries:  make([]HistoryEntry, h.opts.Depth),
+		lastSeen: time.Now(),
+	}
+
+	if len(entries) > h.opts.Depth {
+		entries = entries[len(entries)-h.opts.Depth:]
+	}
+
+	for _, e := range entries {
+		b.add(e)
+	}
+
+	h.buffers[name] = b
+}
+
+func (h *resultHistory) delete(names ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, name := range names {
+		delete(h.buffers, name)
+	}
+}
+
+// evict drops the least recently executed probe, if the maximum number of probes is reached.
+func (h *resultHistory) evict() {
+	if h.opts.MaxProbes <= 0 || len(h.buffers) < h.opts.MaxProbes {
+		return
+	}
+
+	var oldestName string
+	var oldest time.Time
+	for name, b := range h.buffers {
+		if oldestName == "" || b.lastSeen.Before(oldest) {
+			oldestName = name
+			oldest = b.lastSeen
+		}
+	}
+
+	delete(h.buffers, oldestName)
+}
+
+// get returns the history of the probes, or of all the probes if no names are provided.
+func (h *resultHistory) get(names ...string) map[string][]HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	history := map[string][]HistoryEntry{}
+
+	if len(names) == 0 {
+		for name, b := range h.buffers {
+			history[name] = b.list()
+		}
+
+		return history
+	}
+
+	for _, name := range names {
+		b, ok := h.buffers[name]
+		if !ok {
+			continue
+		}
+
+		history[name] = b.list()
+	}
+
+	return history
+}
+
+func newResultHistory(opts HistoryOptions) *resultHistory {
+	h := &resultHistory{
+		opts:    opts,
+		buffers: map[string]*ringBuffer{},
+	}
+
+	return h
+}
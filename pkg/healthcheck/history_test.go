@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNewHistoryEntryTruncatesTheError(t *testing.T) {
+	tests := []struct {
+		name           string
+		message        string
+		maxErrorLength int
+		expected       string
+	}{
+		{
+			name:           "short message",
+			message:        "timeout",
+			maxErrorLength: 10,
+			expected:       "timeout",
+		},
+		{
+			name:           "ascii message",
+			message:        "connection refused",
+			maxErrorLength: 10,
+			expected:       "connection",
+		},
+		{
+			name:           "multi-byte character at the limit",
+			message:        "délai dépassé",
+			maxErrorLength: 8,
+			expected:       "délai d",
+		},
+		{
+			name:           "cut inside a multi-byte character",
+			message:        "délai dépassé",
+			maxErrorLength: 2,
+			expected:       "d",
+		},
+		{
+			name:           "no limit",
+			message:        "délai dépassé",
+			maxErrorLength: 0,
+			expected:       "délai dépassé",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := newHistoryEntry(ExecutionResult{Err: errors.New(tt.message)}, tt.maxErrorLength)
+
+			if e.Error != tt.expected || !utf8.ValidString(e.Error) {
+				t.Fatalf("expected %q, got %q", tt.expected, e.Error)
+			}
+		})
+	}
+}
+
+func TestServiceDeletesTheHistoryOfRemovedProbes(t *testing.T) {
+	probeStore := NewInMemoryProbeStore()
+	err := probeStore.Add(Probe{Name: "db", Kind: ReadinessProbeKind, CheckFn: func(context.Context) error { return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(probeStore, NewNoOpMetricsService())
+
+	_, err = service.ExecuteAllProbes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(service.History("db")["db"]) != 1 {
+		t.Fatal("expected the history of the probe")
+	}
+
+	err = probeStore.Delete("db")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if history := service.History(); len(history) != 0 {
+		t.Fatalf("expected no history, got %v", history)
+	}
+}
@@ -1,14 +1,35 @@
 package healthcheck
 
 import (
+	"context"
 	"log"
+	"net"
 	"net/http"
 
 	"github.com/pkg/errors"
 )
 
-func StartHTTPServer(s *http.Server) {
-	err := s.ListenAndServe()
+// StartHTTPServer starts serving requests.
+//
+// If a listener is provided (e.g. a unix domain socket, or one created by the server builder),
+// the server uses it instead of listening on its own address.
+//
+// If the server has a TLS configuration, it serves HTTPS.
+func StartHTTPServer(s *http.Server, listener ...net.Listener) {
+	var err error
+	isTLS := s.TLSConfig != nil
+
+	switch {
+	case len(listener) > 0 && listener[0] != nil && isTLS:
+		err = s.ServeTLS(listener[0], "", "")
+	case len(listener) > 0 && listener[0] != nil:
+		err = s.Serve(listener[0])
+	case isTLS:
+		err = s.ListenAndServeTLS("", "")
+	default:
+		err = s.ListenAndServe()
+	}
+
 	if err != nil {
 		log.Printf("error listening for health check http server: %s\n", err)
 	}
@@ -22,3 +43,14 @@ func StopHTTPServer(s *http.Server) {
 		}
 	}
 }
+
+// ShutdownHTTPServer gracefully shuts down the server, waiting for the in-flight requests until the context is done.
+func ShutdownHTTPServer(ctx context.Context, s *http.Server) error {
+	err := s.Shutdown(ctx)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("error shutting down health check http server: %s\n", err)
+		return err
+	}
+
+	return nil
+}
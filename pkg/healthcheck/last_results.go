@@ -0,0 +1,62 @@
+package healthcheck
+
+import "sync"
+
+// lastResults keeps the last ExecutionResult of each probe.
+type lastResults struct {
+	mu      sync.RWMutex
+	results map[string]ExecutionResult
+}
+
+// record keeps the results of the probes that were executed.
+// The cached results are ignored, so the minimum interval of a probe starts from its last real execution.
+func (l *lastResults) record(executionResults ...ExecutionResult) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, r := range executionResults {
+		if r.Cached {
+			continue
+		}
+
+		l.results[r.Probe.Name] = r
+	}
+}
+
+func (l *lastResults) get(name string) (ExecutionResult, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	r, ok := l.results[name]
+
+	return r, ok
+}
+
+func (l *lastResults) list() []ExecutionResult {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	executionResults := make([]ExecutionResult, 0, len(l.results))
+	for _, r := range l.results {
+		executionResults = append(executionResults, r)
+	}
+
+	return executionResults
+}
+
+func (l *lastResults) delete(names ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, name := range names {
+		delete(l.results, name)
+	}
+}
+
+func newLastResults() *lastResults {
+	l := &lastResults{
+		results: map[string]ExecutionResult{},
+	}
+
+	return l
+}
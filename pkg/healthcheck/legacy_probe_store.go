@@ -0,0 +1,46 @@
+package healthcheck
+
+// LegacyProbeStore has the API of the ProbeStore before the duplicate detection:
+// Add overwrites the probes with the same name, Get returns a zero Probe for unknown names,
+// and Delete ignores unknown names.
+type LegacyProbeStore interface {
+	Add(probes ...Probe)
+
+	Get(name string) Probe
+	GetAll() []Probe
+
+	// GetByKind returns all probes that have a matching ProbeKind.
+	GetByKind(kind ProbeKind) []Probe
+
+	Delete(names ...string)
+
+	// ProbeStore returns the wrapped ProbeStore, e.g. to pass it to NewService.
+	ProbeStore() ProbeStore
+}
+
+type legacyProbeStore struct {
+	store ProbeStore
+}
+
+func (s legacyProbeStore) Add(probes ...Probe) { s.store.Upsert(probes...) }
+
+func (s legacyProbeStore) Get(name string) Probe {
+	p, _ := s.store.Get(name)
+
+	return p
+}
+
+func (s legacyProbeStore) GetAll() []Probe { return s.store.GetAll() }
+
+func (s legacyProbeStore) GetByKind(kind ProbeKind) []Probe { return s.store.GetByKind(kind) }
+
+func (s legacyProbeStore) Delete(names ...string) { _ = s.store.Delete(names...) }
+
+func (s legacyProbeStore) ProbeStore() ProbeStore { return s.store }
+
+// NewLegacyProbeStore wraps a ProbeStore to keep the behavior of the previous API.
+func NewLegacyProbeStore(store ProbeStore) LegacyProbeStore {
+	return legacyProbeStore{
+		store: store,
+	}
+}
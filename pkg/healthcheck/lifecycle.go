@@ -0,0 +1,151 @@
+package healthcheck
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrDraining = errors.New("draining")
+
+const DrainingProbeName = "draining"
+
+// LifecycleManager implements the Kubernetes pre-stop pattern:
+// on Drain the readiness fails immediately (so the endpoints are deregistered from the load balancers),
+// and after a delay the health check http server(s) are gracefully shut down.
+//
+// The liveness probes keep passing until the servers are shut down.
+type LifecycleManager interface {
+	// Drain fails the readiness, waits for the drain delay, and then shuts down the http server(s).
+	// Calling it more than once has no further effect.
+	Drain(ctx context.Context) error
+
+	IsDraining() bool
+
+	// WaitForSignal blocks until one of the signals is received (SIGTERM and SIGINT by default),
+	// and then calls Drain.
+	// If the context is done first, it returns without draining.
+	WaitForSignal(ctx context.Context, signals ...os.Signal) error
+}
+
+type LifecycleOptions struct {
+	// DrainDelay is how long to wait after failing the readiness and before shutting down,
+	// so that the endpoints have time to be deregistered.
+	DrainDelay time.Duration
+
+	// ShutdownTimeout is how long to wait for the in-flight requests to finish.
+	ShutdownTimeout time.Duration
+}
+
+type lifecycleManager struct {
+	opts    LifecycleOptions
+	servers []*http.Server
+
+	mu       sync.RWMutex
+	draining bool
+
+	once     sync.Once
+	drainErr error
+}
+
+func (m *lifecycleManager) Drain(ctx context.Context) error {
+	m.once.Do(func() {
+		m.mu.Lock()
+		m.draining = true
+		m.mu.Unlock()
+
+		log.Printf("draining: waiting %s before shutting down the health check http server\n", m.opts.DrainDelay)
+
+		select {
+		case <-time.After(m.opts.DrainDelay):
+		case <-ctx.Done():
+		}
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), m.opts.ShutdownTimeout)
+		defer cancel()
+
+		for _, s := range m.servers {
+			err := ShutdownHTTPServer(shutdownCtx, s)
+			if err != nil {
+				m.drainErr = err
+			}
+		}
+	})
+
+	return m.drainErr
+}
+
+func (m *lifecycleManager) IsDraining() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.draining
+}
+
+func (m *lifecycleManager) WaitForSignal(ctx context.Context, signals ...os.Signal) error {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGTERM, syscall.SIGINT}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	defer signal.Stop(c)
+
+	select {
+	case sig := <-c:
+		log.Printf("received signal %s\n", sig)
+		return m.Drain(context.Background())
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *lifecycleManager) checkFn(context.Context) error {
+	if m.IsDraining() {
+		return ErrDraining
+	}
+
+	return nil
+}
+
+// NewLifecycleManager registers a readiness probe in the ProbeStore which fails while draining.
+//
+// It returns ErrDuplicateProbe if the ProbeStore already has a probe named DrainingProbeName.
+func NewLifecycleManager(probeStore ProbeStore, opts LifecycleOptions, servers ...*http.Server) (LifecycleManager, error) {
+	if opts.ShutdownTimeout == 0 {
+		const defaultShutdownTimeout = 10 * time.Second
+		opts.ShutdownTimeout = defaultShutdownTimeout
+	}
+
+	m := &lifecycleManager{
+		opts:    opts,
+		servers: servers,
+	}
+
+	err := addStateProbe(probeStore, DrainingProbeName, m.checkFn)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// addStateProbe registers a readiness probe reporting a state of the application (e.g. draining or maintenance).
+// Its MinInterval is negative, as the state changes must be reported immediately, regardless of the ConcurrencyOptions.
+func addStateProbe(probeStore ProbeStore, name string, fn ProbeCheckFn) error {
+	p := Probe{
+		CheckFn:     fn,
+		Kind:        ReadinessProbeKind,
+		Name:        name,
+		MinInterval: -1,
+	}
+
+	return probeStore.Add(p)
+}
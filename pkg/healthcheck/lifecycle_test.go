@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLifecycleManagerDrainsTheReadinessOnly(t *testing.T) {
+	probeStore := NewInMemoryProbeStore()
+	err := probeStore.Add(Probe{Name: "app", Kind: LivenessProbeKind, CheckFn: func(context.Context) error { return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the draining probe is never cached, even with a minimum interval
+	service := NewService(probeStore, NewNoOpMetricsService(), WithConcurrency(ConcurrencyOptions{MinInterval: time.Hour}))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	go func() { _ = server.Serve(l) }()
+	url := "http://" + l.Addr().String()
+
+	lifecycle, err := NewLifecycleManager(probeStore, LifecycleOptions{DrainDelay: 300 * time.Millisecond}, server)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertHealth := func(kind ProbeKind, expected ProbeHealthStatus) {
+		t.Helper()
+
+		executionResults, err := service.ExecuteProbesByKind(context.Background(), kind)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, r := range executionResults {
+			if r.Probe.Health != expected {
+				t.Fatalf("expected the %s probe %q to be %s, got %s", kind, r.Probe.Name, expected, r.Probe.Health)
+			}
+
+			if expected == UnhealthyStatus && !errors.Is(r.Err, ErrDraining) {
+				t.Fatalf("expected ErrDraining, got %v", r.Err)
+			}
+		}
+	}
+
+	assertHealth(ReadinessProbeKind, HealthyStatus)
+	assertHealth(LivenessProbeKind, HealthyStatus)
+
+	drained := make(chan error)
+	go func() { drained <- lifecycle.Drain(context.Background()) }()
+
+	for !lifecycle.IsDraining() {
+		time.Sleep(time.Millisecond)
+	}
+
+	assertHealth(ReadinessProbeKind, UnhealthyStatus)
+	assertHealth(LivenessProbeKind, HealthyStatus)
+
+	// the server keeps serving during the drain delay
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	err = <-drained
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = http.Get(url)
+	if err == nil {
+		t.Fatal("expected the server to be shut down after the drain")
+	}
+}
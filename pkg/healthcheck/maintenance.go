@@ -0,0 +1,207 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrMaintenance = errors.New("maintenance")
+
+const MaintenanceProbeName = "maintenance"
+
+// MaintenanceStatus is also the content of the sentinel file, if it is not empty.
+type MaintenanceStatus struct {
+	Reason string `json:"reason"`
+
+	// ExpiresAt is when the maintenance ends. The maintenance never ends by itself if it is zero.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+func (m MaintenanceStatus) isExpired(now time.Time) bool {
+	return !m.ExpiresAt.IsZero() && !now.Before(m.ExpiresAt)
+}
+
+// MaintenanceMode makes the readiness fail with a "maintenance" reason, while the liveness stays healthy.
+//
+// The maintenance is reflected by the Prometheus gauge of the MaintenanceProbeName readiness probe.
+type MaintenanceMode interface {
+	// Enable starts the maintenance. The maintenance never ends by itself if expiresAt is zero.
+	Enable(reason string, expiresAt time.Time) error
+
+	Disable() error
+
+	// Status returns the current maintenance, if any.
+	Status() (MaintenanceStatus, bool)
+
+	// WatchSignal toggles the maintenance every time the signal is received, until the context is done.
+	WatchSignal(ctx context.Context, sig os.Signal)
+}
+
+type MaintenanceOptions struct {
+	// SentinelFile enables the maintenance while the file exists, and persists the maintenance across restarts.
+	// The file can be empty, or it can contain a MaintenanceStatus as JSON.
+	// It is only removed by Disable: once the maintenance expires, the file is ignored.
+	SentinelFile string
+}
+
+type maintenanceMode struct {
+	opts MaintenanceOptions
+
+	mu     sync.Mutex
+	status *MaintenanceStatus
+}
+
+func (m *maintenanceMode) Enable(reason string, expiresAt time.Time) error {
+	status := MaintenanceStatus{
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.opts.SentinelFile != "" {
+		content, err := json.Marshal(status)
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(m.opts.SentinelFile, content, 0o644)
+		if err != nil {
+			return errors.Wrap(err, "could not write the maintenance sentinel file")
+		}
+	}
+
+	m.status = &status
+	log.Printf("maintenance enabled: %s\n", reason)
+
+	return nil
+}
+
+func (m *maintenanceMode) Disable() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.disable()
+}
+
+func (m *maintenanceMode) disable() error {
+	if m.opts.SentinelFile != "" {
+		err := os.Remove(m.opts.SentinelFile)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(err, "could not remove the maintenance sentinel file")
+		}
+	}
+
+	if m.status != nil {
+		log.Println("maintenance disabled")
+	}
+
+	m.status = nil
+
+	return nil
+}
+
+func (m *maintenanceMode) Status() (MaintenanceStatus, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.opts.SentinelFile != "" {
+		// the last known maintenance is kept if the file can't be read
+		status, err := readSentinelFile(m.opts.SentinelFile)
+		if err != nil {
+			log.Printf("could not read the maintenance sentinel file: %s\n", err)
+		} else {
+			m.status = status
+		}
+	}
+
+	// an expired maintenance is inactive, but its sentinel file is left to its owner
+	if m.status == nil || m.status.isExpired(time.Now()) {
+		return MaintenanceStatus{}, false
+	}
+
+	return *m.status, true
+}
+
+func (m *maintenanceMode) WatchSignal(ctx context.Context, sig os.Signal) {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, sig)
+	defer signal.Stop(c)
+
+	for {
+		select {
+		case <-c:
+			var err error
+			if _, ok := m.Status(); ok {
+				err = m.Disable()
+			} else {
+				err = m.Enable("signal "+sig.String(), time.Time{})
+			}
+
+			if err != nil {
+				log.Println(err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (m *maintenanceMode) checkFn(context.Context) error {
+	status, ok := m.Status()
+	if !ok {
+		return nil
+	}
+
+	return errors.Wrap(ErrMaintenance, status.Reason)
+}
+
+// readSentinelFile returns a nil status if the file doesn't exist.
+func readSentinelFile(path string) (*MaintenanceStatus, error) {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := MaintenanceStatus{
+		Reason: "sentinel file " + path,
+	}
+
+	if strings.TrimSpace(string(content)) != "" {
+		err = json.Unmarshal(content, &status)
+		if err != nil {
+			log.Printf("could not parse the maintenance sentinel file: %s\n", err)
+		}
+	}
+
+	return &status, nil
+}
+
+// NewMaintenanceMode registers a readiness probe in the ProbeStore which fails during the maintenance.
+//
+// It returns ErrDuplicateProbe if the ProbeStore already has a probe named MaintenanceProbeName.
+func NewMaintenanceMode(probeStore ProbeStore, opts MaintenanceOptions) (MaintenanceMode, error) {
+	m := &maintenanceMode{
+		opts: opts,
+	}
+
+	err := addStateProbe(probeStore, MaintenanceProbeName, m.checkFn)
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
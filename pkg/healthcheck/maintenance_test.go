@@ -0,0 +1,101 @@
+package healthcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceModeSentinelFile(t *testing.T) {
+	expired := `{"reason": "upgrade", "expires_at": "` + time.Now().Add(-time.Minute).Format(time.RFC3339) + `"}`
+	active := `{"reason": "upgrade", "expires_at": "` + time.Now().Add(time.Hour).Format(time.RFC3339) + `"}`
+
+	tests := []struct {
+		name     string
+		content  *string
+		isActive bool
+		reason   string
+	}{
+		{
+			name: "no file",
+		},
+		{
+			name:     "empty file",
+			content:  new(string),
+			isActive: true,
+		},
+		{
+			name:     "active maintenance",
+			content:  &active,
+			isActive: true,
+			reason:   "upgrade",
+		},
+		{
+			name:    "expired maintenance",
+			content: &expired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sentinelFile := filepath.Join(t.TempDir(), "maintenance")
+			if tt.content != nil {
+				err := os.WriteFile(sentinelFile, []byte(*tt.content), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			m, err := NewMaintenanceMode(NewInMemoryProbeStore(), MaintenanceOptions{SentinelFile: sentinelFile})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			status, isActive := m.Status()
+			if isActive != tt.isActive {
+				t.Fatalf("expected active=%t, got %t", tt.isActive, isActive)
+			}
+
+			if tt.reason != "" && status.Reason != tt.reason {
+				t.Fatalf("expected the reason %q, got %q", tt.reason, status.Reason)
+			}
+
+			// the library never removes the sentinel file by itself
+			_, err = os.Stat(sentinelFile)
+			if (tt.content != nil) != (err == nil) {
+				t.Fatalf("expected the sentinel file to be kept, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMaintenanceModeKeepsTheStateIfTheSentinelFileCantBeRead(t *testing.T) {
+	sentinelFile := filepath.Join(t.TempDir(), "maintenance")
+
+	m, err := NewMaintenanceMode(NewInMemoryProbeStore(), MaintenanceOptions{SentinelFile: sentinelFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Enable("upgrade", time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a directory can't be read as a file, as would a file without read permission
+	err = os.Remove(sentinelFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.Mkdir(sentinelFile, 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, isActive := m.Status()
+	if !isActive || status.Reason != "upgrade" {
+		t.Fatalf("expected the maintenance to stay active, got %t (%q)", isActive, status.Reason)
+	}
+}
@@ -0,0 +1,30 @@
+package healthcheck
+
+// Severity is the impact of a failed probe.
+type Severity string
+
+const (
+	CriticalSeverity Severity = "critical"
+	WarningSeverity  Severity = "warning"
+	InfoSeverity     Severity = "info"
+)
+
+// ProbeMetadata describes a probe for the people on call.
+// All the fields are optional.
+type ProbeMetadata struct {
+	Description string `json:"description,omitempty"`
+
+	// Owner is the team or person responsible for the probe.
+	Owner string `json:"owner,omitempty"`
+
+	RunbookURL string   `json:"runbook_url,omitempty"`
+	Severity   Severity `json:"severity,omitempty"`
+
+	// Labels are arbitrary key-value pairs, e.g. the service or the region of a dependency.
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// IsZero reports whether none of the fields are set.
+func (m ProbeMetadata) IsZero() bool {
+	return m.Description == "" && m.Owner == "" && m.RunbookURL == "" && m.Severity == "" && len(m.Labels) == 0
+}
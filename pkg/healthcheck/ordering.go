@@ -0,0 +1,33 @@
+package healthcheck
+
+import (
+	"sort"
+)
+
+// ResultOrder is the order of the ExecutionResult(s) returned by the Service.
+type ResultOrder string
+
+const (
+	// OrderByKindAndName sorts the results by ProbeKind, then by probe name.
+	OrderByKindAndName ResultOrder = "kind_name"
+
+	// OrderByRegistration keeps the order of the probes given to ExecuteProbes,
+	// which is the order in which they were added to the ProbeStore for the other methods of the Service.
+	OrderByRegistration ResultOrder = "registration"
+)
+
+// sortResults sorts the results in place.
+func (o ResultOrder) sortResults(executionResults []ExecutionResult) {
+	if o != OrderByKindAndName {
+		return
+	}
+
+	sort.SliceStable(executionResults, func(i, j int) bool {
+		a, b := executionResults[i].Probe, executionResults[j].Probe
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+
+		return a.Name < b.Name
+	})
+}
@@ -0,0 +1,137 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var ErrInvalidOverride = errors.New("invalid override")
+
+// OverrideMode changes how a probe is executed, e.g. to take a dependency out of the readiness during maintenance.
+type OverrideMode string
+
+const (
+	// DisabledOverride skips the execution of the probe. Its status is SkippedStatus.
+	DisabledOverride OverrideMode = "disabled"
+
+	// MutedOverride executes the probe, but its status doesn't affect the overall status.
+	MutedOverride OverrideMode = "muted"
+
+	// ForcedOverride skips the execution of the probe, and reports the forced status instead.
+	ForcedOverride OverrideMode = "forced"
+)
+
+type Override struct {
+	Mode OverrideMode `json:"mode"`
+
+	// Status is the status reported for the ForcedOverride.
+	Status ProbeHealthStatus `json:"status,omitempty"`
+
+	Reason string `json:"reason"`
+
+	// ExpiresAt is when the override is removed. The override never expires if it is zero.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// MarshalJSON omits the ExpiresAt if it is zero.
+func (o Override) MarshalJSON() ([]byte, error) {
+	type override Override
+	v := struct {
+		override
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}{
+		override: override(o),
+	}
+
+	if !o.ExpiresAt.IsZero() {
+		v.ExpiresAt = &o.ExpiresAt
+	}
+
+	return json.Marshal(v)
+}
+
+func (o Override) isExpired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt)
+}
+
+func (o Override) validate() error {
+	switch o.Mode {
+	case DisabledOverride, MutedOverride:
+	case ForcedOverride:
+		if o.Status != HealthyStatus && o.Status != UnhealthyStatus {
+			return errors.Wrapf(ErrInvalidOverride, "forced status must be %q or %q", HealthyStatus, UnhealthyStatus)
+		}
+	default:
+		return errors.Wrapf(ErrInvalidOverride, "unknown mode %q", o.Mode)
+	}
+
+	return nil
+}
+
+type overrideStore struct {
+	mu        sync.Mutex
+	overrides map[string]Override
+}
+
+func (s *overrideStore) set(name string, o Override) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.overrides[name] = o
+}
+
+// get returns the override of the probe, if it has one that didn't expire.
+func (s *overrideStore) get(name string) (Override, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o, ok := s.overrides[name]
+	if !ok {
+		return Override{}, false
+	}
+
+	if o.isExpired(time.Now()) {
+		delete(s.overrides, name)
+		return Override{}, false
+	}
+
+	return o, true
+}
+
+func (s *overrideStore) list() map[string]Override {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	overrides := make(map[string]Override, len(s.overrides))
+	for name, o := range s.overrides {
+		if o.isExpired(now) {
+			delete(s.overrides, name)
+			continue
+		}
+
+		overrides[name] = o
+	}
+
+	return overrides
+}
+
+func (s *overrideStore) delete(names ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		delete(s.overrides, name)
+	}
+}
+
+func newOverrideStore() *overrideStore {
+	s := &overrideStore{
+		overrides: map[string]Override{},
+	}
+
+	return s
+}
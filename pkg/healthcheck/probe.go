@@ -2,6 +2,7 @@ package healthcheck
 
 import (
 	"context"
+	"time"
 )
 
 type ProbeCheckFn func(context.Context) error
@@ -31,6 +32,9 @@ type ProbeHealthStatus string
 const (
 	HealthyStatus   ProbeHealthStatus = "healthy"
 	UnhealthyStatus ProbeHealthStatus = "unhealthy"
+
+	// SkippedStatus is the status of the probes that are not executed (see DisabledOverride).
+	SkippedStatus ProbeHealthStatus = "skipped"
 )
 
 type Probe struct {
@@ -38,6 +42,16 @@ type Probe struct {
 	Kind    ProbeKind
 	Name    string
 	Health  ProbeHealthStatus
+
+	// HideDetails replaces the error message of the probe with a generic one.
+	HideDetails bool
+
+	// MinInterval is the minimum interval between two executions of the probe (see ConcurrencyOptions).
+	// If it is 0, the one of the Service is used, and if it is negative, the probe is always executed.
+	MinInterval time.Duration
+
+	// Metadata is shown in the detailed responses and in the dashboard.
+	Metadata ProbeMetadata
 }
 
 func (p Probe) Execute(ctx context.Context) error {
@@ -1,55 +1,156 @@
 package healthcheck
 
-import "sync"
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrProbeNotFound  = errors.New("probe not found")
+	ErrDuplicateProbe = errors.New("duplicate probe")
+)
 
 type ProbeStore interface {
-	Add(probes ...Probe)
+	// Add returns ErrDuplicateProbe if a probe with the same name is already in the store,
+	// in which case none of the probes are added.
+	Add(probes ...Probe) error
+
+	// Upsert adds the probes, replacing the ones with the same name.
+	// Only the probes that were not in the store are reported as added (see ProbeAddedEvent).
+	Upsert(probes ...Probe)
 
-	Get(name string) Probe
+	Get(name string) (Probe, bool)
+
+	// GetAll returns all the probes, in the order in which they were added.
 	GetAll() []Probe
 
-	// GetByKind returns all probes that have a matching ProbeKind.
+	// GetByKind returns all probes that have a matching ProbeKind, in the order in which they were added.
 	GetByKind(kind ProbeKind) []Probe
 
-	Delete(names ...string)
+	// Delete removes the probes, and returns ErrProbeNotFound if any of them is not in the store.
+	Delete(names ...string) error
+
+	// Snapshot returns a consistent view of all the probes.
+	Snapshot() ProbeStoreSnapshot
+}
+
+// ProbeStoreSnapshot is a consistent view of a ProbeStore.
+//
+// The Version is incremented on every change of the store, so it can be used to detect changes.
+type ProbeStoreSnapshot struct {
+	Version uint64
+	Probes  []Probe
+}
+
+// probeStoreWatcher is implemented by the ProbeStore(s) that notify when probes are added or removed.
+type probeStoreWatcher interface {
+	watch(fn func(added, removed []Probe))
 }
 
 type inMemoryProbeStore struct {
 	mu sync.RWMutex
 
 	probes map[string]Probe
+
+	// sequence has the registration order of the probes, which is kept when a probe is replaced.
+	sequence     map[string]uint64
+	nextSequence uint64
+
+	version  uint64
+	watchers []func(added, removed []Probe)
 }
 
-func (s *inMemoryProbeStore) Add(probes ...Probe) {
+func (s *inMemoryProbeStore) Add(probes ...Probe) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
 
+	names := make(map[string]struct{}, len(probes))
 	for _, p := range probes {
+		_, isStored := s.probes[p.Name]
+		_, isAdded := names[p.Name]
+		if isStored || isAdded {
+			s.mu.Unlock()
+			return errors.Wrapf(ErrDuplicateProbe, "probe %q", p.Name)
+		}
+
+		names[p.Name] = struct{}{}
+	}
+
+	added := s.add(probes)
+	s.mu.Unlock()
+
+	s.notify(added, nil)
+
+	return nil
+}
+
+func (s *inMemoryProbeStore) Upsert(probes ...Probe) {
+	s.mu.Lock()
+	added := s.add(probes)
+	s.mu.Unlock()
+
+	s.notify(added, nil)
+}
+
+// add must be called with the lock held.
+// It returns the probes that were not already stored, as the replaced ones are not reported as added.
+func (s *inMemoryProbeStore) add(probes []Probe) []Probe {
+	if len(probes) == 0 {
+		return nil
+	}
+
+	var added []Probe
+	for _, p := range probes {
+		if _, ok := s.probes[p.Name]; !ok {
+			s.sequence[p.Name] = s.nextSequence
+			s.nextSequence++
+			added = append(added, p)
+		}
+
 		s.probes[p.Name] = p
 	}
+
+	s.version++
+
+	return added
 }
 
-func (s *inMemoryProbeStore) Get(name string) Probe {
+func (s *inMemoryProbeStore) Get(name string) (Probe, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	p := s.probes[name]
+	p, ok := s.probes[name]
 
-	return p
+	return p, ok
 }
 
 func (s *inMemoryProbeStore) GetAll() []Probe {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.getAll()
+}
+
+// getAll must be called with the lock held.
+func (s *inMemoryProbeStore) getAll() []Probe {
 	probeList := make([]Probe, 0, len(s.probes))
 	for _, p := range s.probes {
 		probeList = append(probeList, p)
 	}
 
+	s.sortByRegistration(probeList)
+
 	return probeList
 }
 
+// sortByRegistration must be called with the lock held.
+func (s *inMemoryProbeStore) sortByRegistration(probes []Probe) {
+	sort.Slice(probes, func(i, j int) bool {
+		return s.sequence[probes[i].Name] < s.sequence[probes[j].Name]
+	})
+}
+
 func (s *inMemoryProbeStore) GetByKind(kind ProbeKind) []Probe {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -63,22 +164,76 @@ func (s *inMemoryProbeStore) GetByKind(kind ProbeKind) []Probe {
 		probeList = append(probeList, p)
 	}
 
+	s.sortByRegistration(probeList)
+
 	return probeList
 }
 
-func (s *inMemoryProbeStore) Delete(names ...string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *inMemoryProbeStore) Delete(names ...string) error {
+	var err error
 
+	s.mu.Lock()
+	removed := make([]Probe, 0, len(names))
 	for _, name := range names {
+		p, ok := s.probes[name]
+		if !ok {
+			err = errors.Wrapf(ErrProbeNotFound, "probe %q", name)
+			continue
+		}
+
 		delete(s.probes, name)
+		delete(s.sequence, name)
+		removed = append(removed, p)
+	}
+
+	if len(removed) > 0 {
+		s.version++
+	}
+	s.mu.Unlock()
+
+	s.notify(nil, removed)
+
+	return err
+}
+
+func (s *inMemoryProbeStore) Snapshot() ProbeStoreSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	snapshot := ProbeStoreSnapshot{
+		Version: s.version,
+		Probes:  s.getAll(),
+	}
+
+	return snapshot
+}
+
+func (s *inMemoryProbeStore) watch(fn func(added, removed []Probe)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watchers = append(s.watchers, fn)
+}
+
+func (s *inMemoryProbeStore) notify(added, removed []Probe) {
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	watchers := s.watchers
+	s.mu.RUnlock()
+
+	for _, fn := range watchers {
+		fn(added, removed)
 	}
 }
 
 func NewInMemoryProbeStore() ProbeStore {
 	s := &inMemoryProbeStore{
-		mu:     sync.RWMutex{},
-		probes: map[string]Probe{},
+		mu:       sync.RWMutex{},
+		probes:   map[string]Probe{},
+		sequence: map[string]uint64{},
 	}
 
 	return s
@@ -0,0 +1,86 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInMemoryProbeStoreReportsTheAddedProbes(t *testing.T) {
+	tests := []struct {
+		name     string
+		upsert   []string
+		expected []string
+	}{
+		{
+			name:     "new probes",
+			upsert:   []string{"cache", "queue"},
+			expected: []string{"cache", "queue"},
+		},
+		{
+			name:   "replaced probe",
+			upsert: []string{"db"},
+		},
+		{
+			name:     "new and replaced probes",
+			upsert:   []string{"db", "cache"},
+			expected: []string{"cache"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			probeStore := NewInMemoryProbeStore()
+			err := probeStore.Add(Probe{Name: "db", Kind: ReadinessProbeKind})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var added []string
+			probeStore.(probeStoreWatcher).watch(func(probes, _ []Probe) {
+				for _, p := range probes {
+					added = append(added, p.Name)
+				}
+			})
+
+			probes := make([]Probe, 0, len(tt.upsert))
+			for _, name := range tt.upsert {
+				probes = append(probes, Probe{Name: name, Kind: ReadinessProbeKind})
+			}
+			probeStore.Upsert(probes...)
+
+			if len(added) != len(tt.expected) {
+				t.Fatalf("expected %v to be reported as added, got %v", tt.expected, added)
+			}
+
+			for i := range added {
+				if added[i] != tt.expected[i] {
+					t.Fatalf("expected %v to be reported as added, got %v", tt.expected, added)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStateProbesRejectADuplicateProbe(t *testing.T) {
+	probeStore := NewInMemoryProbeStore()
+
+	_, err := NewLifecycleManager(probeStore, LifecycleOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewMaintenanceMode(probeStore, MaintenanceOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewLifecycleManager(probeStore, LifecycleOptions{})
+	if !errors.Is(err, ErrDuplicateProbe) {
+		t.Fatalf("expected ErrDuplicateProbe for the draining probe, got %v", err)
+	}
+
+	_, err = NewMaintenanceMode(probeStore, MaintenanceOptions{})
+	if !errors.Is(err, ErrDuplicateProbe) {
+		t.Fatalf("expected ErrDuplicateProbe for the maintenance probe, got %v", err)
+	}
+}
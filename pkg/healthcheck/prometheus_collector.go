@@ -0,0 +1,136 @@
+package healthcheck
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ScrapeOptions struct {
+	// Timeout is the maximum duration of the execution of the probes, during a scrape.
+	Timeout time.Duration
+
+	// CacheWindow is the interval during which the scrapes reuse the results of the previous execution.
+	// The probes are executed on every scrape if it is not positive.
+	CacheWindow time.Duration
+}
+
+func DefaultScrapeOptions() ScrapeOptions {
+	return ScrapeOptions{
+		Timeout:     5 * time.Second,
+		CacheWindow: 10 * time.Second,
+	}
+}
+
+// serviceBinder is implemented by the MetricsService(s) that execute the probes of the Service.
+type serviceBinder interface {
+	bindService(service Service)
+}
+
+// metricsCollector collects the metrics of the prometheusMetricsService.
+type metricsCollector struct {
+	metrics    *prometheusMetricsService
+	collectors []prometheus.Collector
+}
+
+func (c *metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
+}
+
+func (c *metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c.collectors {
+		collector.Collect(ch)
+	}
+}
+
+// scrapeCollector executes all the probes of the Service when it is collected,
+// and then collects the metrics of the prometheusMetricsService.
+type scrapeCollector struct {
+	opts       ScrapeOptions
+	metrics    *prometheusMetricsService
+	collectors []prometheus.Collector
+
+	mu         sync.Mutex
+	service    Service
+	executedAt time.Time
+}
+
+func (c *scrapeCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c.collectors {
+		collector.Describe(ch)
+	}
+}
+
+func (c *scrapeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.execute()
+
+	for _, collector := range c.collectors {
+		collector.Collect(ch)
+	}
+}
+
+func (c *scrapeCollector) bind(service Service) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.service = service
+	c.executedAt = time.Time{}
+}
+
+func (c *scrapeCollector) execute() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.service == nil || time.Since(c.executedAt) < c.opts.CacheWindow {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.Timeout)
+	defer cancel()
+
+	// the metrics are updated by the Service, before ExecuteAllProbes returns
+	_, err := c.service.ExecuteAllProbes(ctx)
+	if err != nil {
+		log.Printf("could not execute the probes: %s\n", err)
+		return
+	}
+	c.executedAt = time.Now()
+}
+
+func newScrapeCollector(opts ScrapeOptions, metrics *prometheusMetricsService, collectors []prometheus.Collector) *scrapeCollector {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultScrapeOptions().Timeout
+	}
+
+	c := &scrapeCollector{
+		opts:       opts,
+		metrics:    metrics,
+		collectors: collectors,
+	}
+
+	return c
+}
+
+// collectorList is a prometheus.Registerer that keeps the collectors, without registering them.
+type collectorList struct {
+	collectors []prometheus.Collector
+}
+
+func (l *collectorList) Register(c prometheus.Collector) error {
+	l.collectors = append(l.collectors, c)
+
+	return nil
+}
+
+func (l *collectorList) MustRegister(cs ...prometheus.Collector) {
+	l.collectors = append(l.collectors, cs...)
+}
+
+func (l *collectorList) Unregister(prometheus.Collector) bool {
+	return false
+}
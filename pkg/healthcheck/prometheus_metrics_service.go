@@ -0,0 +1,466 @@
+package healthcheck
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// PrometheusMetricNames are the names of the metrics, without the namespace and the subsystem.
+type PrometheusMetricNames struct {
+	Status      string
+	Flapping    string
+	Override    string
+	CheckErrors string
+	ProbeInfo   string
+}
+
+type PrometheusOptions struct {
+	Namespace string
+	Subsystem string
+	Names     PrometheusMetricNames
+
+	// ConstLabels are added to all the metrics (e.g. the service or the region).
+	ConstLabels prometheus.Labels
+
+	// ProbeLabels are the keys of the ProbeMetadata.Labels that are added as labels to all the metrics.
+	// The value is empty for the probes without the label.
+	ProbeLabels []string
+
+	// Registerer is where the metrics are registered, and Gatherer is where the handler reads them from.
+	// By default, the Prometheus default registry is used.
+	//
+	// The MetricsService(s) registered with the same options share their metrics,
+	// and the last time the metrics of each probe were updated (see StaleTTL).
+	Registerer  prometheus.Registerer
+	Gatherer    prometheus.Gatherer
+	HandlerOpts promhttp.HandlerOpts
+
+	// StaleTTL deletes the metrics of the probes that were not executed for this long,
+	// when the metrics are updated or served by the handler.
+	// It is disabled if it is not positive.
+	StaleTTL time.Duration
+
+	// ExecuteOnScrape executes the probes when the metrics are collected,
+	// so the metrics are fresh even if the health check endpoints are not called.
+	// It is disabled if it is nil.
+	ExecuteOnScrape *ScrapeOptions
+}
+
+func DefaultPrometheusOptions(namespace string) PrometheusOptions {
+	return PrometheusOptions{
+		Namespace: namespace,
+		Subsystem: "healthcheck",
+		Names: PrometheusMetricNames{
+			Status:      "status",
+			Flapping:    "flapping",
+			Override:    "override",
+			CheckErrors: "check_errors_total",
+			ProbeInfo:   "probe_info",
+		},
+	}
+}
+
+type prometheusMetricsService struct {
+	probeLabels   []string
+	statusGauge   *prometheus.GaugeVec
+	flappingGauge *prometheus.GaugeVec
+	overrideGauge *prometheus.GaugeVec
+	errorCounter  *prometheus.CounterVec
+	infoGauge     *prometheus.GaugeVec
+	infoLabels    *infoLabels
+	lastSeen      *lastSeen
+	staleTTL      time.Duration
+	collector     *scrapeCollector
+	handler       http.Handler
+}
+
+// lastSeen has the last time the metrics of every probe were updated.
+type lastSeen struct {
+	mu    sync.Mutex
+	times map[string]time.Time
+}
+
+func (l *lastSeen) touch(name string, t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.times[name] = t
+}
+
+func (l *lastSeen) delete(name string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.times, name)
+}
+
+// expire removes and returns the names of the probes that were not seen since the threshold.
+func (l *lastSeen) expire(threshold time.Time) []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var names []string
+	for name, t := range l.times {
+		if t.Before(threshold) {
+			names = append(names, name)
+			delete(l.times, name)
+		}
+	}
+
+	return names
+}
+
+func newLastSeen() *lastSeen {
+	return &lastSeen{times: map[string]time.Time{}}
+}
+
+// infoLabels has the label values of the info gauge of every probe,
+// so the series are only replaced when the metadata of the probe changes.
+type infoLabels struct {
+	mu     sync.Mutex
+	values map[string][]string
+}
+
+// update replaces the series of the probe in the gauge, if its label values changed.
+func (l *infoLabels) update(gauge *prometheus.GaugeVec, name string, values []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	previous, ok := l.values[name]
+	if ok && equalStrings(previous, values) {
+		return
+	}
+
+	if ok {
+		gauge.DeleteLabelValues(previous...)
+		delete(l.values, name)
+	}
+
+	if values != nil {
+		gauge.WithLabelValues(values...).Set(1)
+		l.values[name] = values
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newInfoLabels() *infoLabels {
+	return &infoLabels{values: map[string][]string{}}
+}
+
+func (s prometheusMetricsService) GetHandler() http.Handler {
+	return s.handler
+}
+
+func (s prometheusMetricsService) UpdateGauge(executionResults ...ExecutionResult) {
+	defer s.deleteStaleMetrics()
+
+	wg := sync.WaitGroup{}
+
+	for _, executionResult := range executionResults {
+		wg.Add(1)
+
+		go func(e ExecutionResult) {
+			defer wg.Done()
+
+			p := e.Probe
+			s.lastSeen.touch(p.Name, time.Now())
+
+			switch p.Health {
+			case HealthyStatus:
+				s.statusGauge.WithLabelValues(s.labelValues(p)...).Set(0)
+			case UnhealthyStatus:
+				s.statusGauge.WithLabelValues(s.labelValues(p)...).Set(1)
+			}
+
+			var flapping float64
+			if e.Flapping {
+				flapping = 1
+			}
+			s.flappingGauge.WithLabelValues(s.labelValues(p)...).Set(flapping)
+
+			for _, mode := range []OverrideMode{DisabledOverride, MutedOverride, ForcedOverride} {
+				if e.Override != nil && e.Override.Mode == mode {
+					s.overrideGauge.WithLabelValues(s.labelValues(p, string(mode))...).Set(1)
+				} else {
+					s.overrideGauge.DeleteLabelValues(s.labelValues(p, string(mode))...)
+				}
+			}
+
+			s.updateInfo(p)
+
+			if e.Err != nil && e.Executed() {
+				s.errorCounter.WithLabelValues(s.labelValues(p, string(ErrorCodeOf(e.Err)))...).Inc()
+			}
+		}(executionResult)
+	}
+
+	wg.Wait()
+}
+
+func (s prometheusMetricsService) bindService(service Service) {
+	if s.collector != nil {
+		s.collector.bind(service)
+	}
+}
+
+func (s prometheusMetricsService) DeleteProbeMetrics(probes ...Probe) {
+	for _, p := range probes {
+		s.deleteMetrics(p.Name)
+		s.lastSeen.delete(p.Name)
+	}
+}
+
+func (s prometheusMetricsService) deleteMetrics(name string) {
+	labels := prometheus.Labels{"probe": name}
+
+	s.statusGauge.DeletePartialMatch(labels)
+	s.flappingGauge.DeletePartialMatch(labels)
+	s.overrideGauge.DeletePartialMatch(labels)
+	s.errorCounter.DeletePartialMatch(labels)
+	s.infoLabels.update(s.infoGauge, name, nil)
+}
+
+// deleteStaleMetrics deletes the metrics of the probes that were not executed within the StaleTTL.
+func (s prometheusMetricsService) deleteStaleMetrics() {
+	if s.staleTTL <= 0 {
+		return
+	}
+
+	for _, name := range s.lastSeen.expire(time.Now().Add(-s.staleTTL)) {
+		s.deleteMetrics(name)
+	}
+}
+
+// labelValues returns the values of the kind, probe, and probe labels, followed by the extra values.
+func (s prometheusMetricsService) labelValues(p Probe, extra ...string) []string {
+	values := make([]string, 0, 2+len(s.probeLabels)+len(extra))
+	values = append(values, string(p.Kind), p.Name)
+	for _, key := range s.probeLabels {
+		values = append(values, p.Metadata.Labels[key])
+	}
+
+	return append(values, extra...)
+}
+
+// updateInfo sets the info gauge of the probes with metadata.
+func (s prometheusMetricsService) updateInfo(p Probe) {
+	var values []string
+	if m := p.Metadata; m.Description != "" || m.Owner != "" || m.RunbookURL != "" || m.Severity != "" {
+		values = s.labelValues(p, m.Description, m.Owner, m.RunbookURL, string(m.Severity))
+	}
+
+	s.infoLabels.update(s.infoGauge, p.Name, values)
+}
+
+func NewPrometheusMetricsService(namespace string) MetricsService {
+	s, err := NewPrometheusMetricsServiceWithOptions(DefaultPrometheusOptions(namespace))
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func NewPrometheusMetricsServiceWithHandler(namespace string, reg *prometheus.Registry, opts promhttp.HandlerOpts) MetricsService {
+	o := DefaultPrometheusOptions(namespace)
+	o.Registerer = reg
+	o.Gatherer = reg
+	o.HandlerOpts = opts
+
+	s, err := NewPrometheusMetricsServiceWithOptions(o)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+// NewPrometheusMetricsServiceWithOptions returns an error if the metrics could not be registered,
+// e.g. if a metric with the same name but different labels is already registered.
+//
+// The names that are not set in the options are taken from the DefaultPrometheusOptions.
+func NewPrometheusMetricsServiceWithOptions(opts PrometheusOptions) (MetricsService, error) {
+	defaults := DefaultPrometheusOptions(opts.Namespace)
+	setDefault(&opts.Subsystem, defaults.Subsystem)
+	setDefault(&opts.Names.Status, defaults.Names.Status)
+	setDefault(&opts.Names.Flapping, defaults.Names.Flapping)
+	setDefault(&opts.Names.Override, defaults.Names.Override)
+	setDefault(&opts.Names.CheckErrors, defaults.Names.CheckErrors)
+	setDefault(&opts.Names.ProbeInfo, defaults.Names.ProbeInfo)
+
+	registerer := opts.Registerer
+	if registerer == nil {
+		registerer = prometheus.DefaultRegisterer
+	}
+
+	gatherer := opts.Gatherer
+	if gatherer == nil {
+		gatherer = prometheus.DefaultGatherer
+	}
+
+	// the metrics are registered together, by a metricsCollector or a scrapeCollector,
+	// so the state kept next to them is shared with the MetricsService(s) that reuse them
+	collectors := &collectorList{}
+	metricsRegisterer := collectors
+
+	labels := append([]string{"kind", "probe"}, opts.ProbeLabels...)
+	withLabels := func(extra ...string) []string {
+		return append(append([]string(nil), labels...), extra...)
+	}
+
+	s := &prometheusMetricsService{
+		probeLabels: append([]string(nil), opts.ProbeLabels...),
+		infoLabels:  newInfoLabels(),
+		lastSeen:    newLastSeen(),
+		staleTTL:    opts.StaleTTL,
+	}
+
+	var err error
+
+	s.statusGauge, err = registerGaugeVec(metricsRegisterer, opts, opts.Names.Status,
+		fmt.Sprintf("Current probe check status (0=%s, 1=%s)", HealthyStatus, UnhealthyStatus), withLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	s.flappingGauge, err = registerGaugeVec(metricsRegisterer, opts, opts.Names.Flapping,
+		"Whether the probe changes its status too often (0=stable, 1=flapping)", withLabels())
+	if err != nil {
+		return nil, err
+	}
+
+	s.overrideGauge, err = registerGaugeVec(metricsRegisterer, opts, opts.Names.Override,
+		"Whether the probe has an override (1=disabled, muted, or forced, as set by the mode label)", withLabels("mode"))
+	if err != nil {
+		return nil, err
+	}
+
+	s.infoGauge, err = registerGaugeVec(metricsRegisterer, opts, opts.Names.ProbeInfo,
+		"Metadata of the probes, as labels (always 1)", withLabels("description", "owner", "runbook_url", "severity"))
+	if err != nil {
+		return nil, err
+	}
+
+	errorCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace:   opts.Namespace,
+		Subsystem:   opts.Subsystem,
+		Name:        opts.Names.CheckErrors,
+		Help:        "Number of failed probe checks, by error code",
+		ConstLabels: opts.ConstLabels,
+	}, withLabels("code"))
+
+	collector, err := register(metricsRegisterer, errorCounter)
+	if err != nil {
+		return nil, err
+	}
+
+	var ok bool
+	s.errorCounter, ok = collector.(*prometheus.CounterVec)
+	if !ok {
+		return nil, errors.Errorf("the metric %s is already registered by another collector", opts.Names.CheckErrors)
+	}
+
+	var c prometheus.Collector = &metricsCollector{metrics: s, collectors: collectors.collectors}
+	if opts.ExecuteOnScrape != nil {
+		s.collector = newScrapeCollector(*opts.ExecuteOnScrape, s, collectors.collectors)
+		c = s.collector
+	}
+
+	collector, err = register(registerer, c)
+	if err != nil {
+		return nil, err
+	}
+
+	// reuse the metrics of the MetricsService that registered the same collector
+	var existing *prometheusMetricsService
+	switch collector := collector.(type) {
+	case *metricsCollector:
+		if opts.ExecuteOnScrape == nil {
+			existing = collector.metrics
+		}
+	case *scrapeCollector:
+		if opts.ExecuteOnScrape != nil {
+			existing = collector.metrics
+		}
+	}
+
+	if existing == nil {
+		return nil, errors.New("the metrics are already registered by another collector")
+	}
+	*s = *existing
+	s.staleTTL = opts.StaleTTL
+
+	handler := promhttp.HandlerFor(gatherer, opts.HandlerOpts)
+	if opts.Registerer == nil && opts.Gatherer == nil {
+		handler = promhttp.InstrumentMetricHandler(registerer, handler)
+	}
+
+	s.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.deleteStaleMetrics()
+		handler.ServeHTTP(w, r)
+	})
+
+	return s, nil
+}
+
+func registerGaugeVec(registerer prometheus.Registerer, opts PrometheusOptions, name, help string, labels []string) (*prometheus.GaugeVec, error) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace:   opts.Namespace,
+		Subsystem:   opts.Subsystem,
+		Name:        name,
+		Help:        help,
+		ConstLabels: opts.ConstLabels,
+	}, labels)
+
+	collector, err := register(registerer, gauge)
+	if err != nil {
+		return nil, err
+	}
+
+	gauge, ok := collector.(*prometheus.GaugeVec)
+	if !ok {
+		return nil, errors.Errorf("the metric %s is already registered by another collector", name)
+	}
+
+	return gauge, nil
+}
+
+// register returns the collector that is already registered, if there is one.
+func register(registerer prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	err := registerer.Register(c)
+	if err == nil {
+		return c, nil
+	}
+
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		return are.ExistingCollector, nil
+	}
+
+	return nil, errors.Wrap(err, "could not register the metric")
+}
+
+func setDefault(value *string, defaultValue string) {
+	if *value == "" {
+		*value = defaultValue
+	}
+}
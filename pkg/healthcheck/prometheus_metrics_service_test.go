@@ -0,0 +1,206 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// statusSeries returns the names of the probes with a status metric in the registry.
+func statusSeries(t *testing.T, reg *prometheus.Registry) map[string]bool {
+	t.Helper()
+
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := map[string]bool{}
+	for _, f := range families {
+		if f.GetName() != "test_healthcheck_status" {
+			continue
+		}
+
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "probe" {
+					probes[l.GetValue()] = true
+				}
+			}
+		}
+	}
+
+	return probes
+}
+
+func TestPrometheusMetricsServicesShareTheStaleMetrics(t *testing.T) {
+	const staleTTL = 100 * time.Millisecond
+
+	reg := prometheus.NewRegistry()
+	opts := DefaultPrometheusOptions("test")
+	opts.Registerer = reg
+	opts.Gatherer = reg
+	opts.StaleTTL = staleTTL
+
+	first, err := NewPrometheusMetricsServiceWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := NewPrometheusMetricsServiceWithOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healthy := func(name string) ExecutionResult {
+		return ExecutionResult{Probe: Probe{Name: name, Kind: ReadinessProbeKind, Health: HealthyStatus}}
+	}
+
+	first.UpdateGauge(healthy("db"), healthy("cache"))
+	time.Sleep(staleTTL + 50*time.Millisecond)
+
+	// the metrics of "db" are kept up to date by the second MetricsService only
+	second.UpdateGauge(healthy("db"))
+	first.UpdateGauge(healthy("queue"))
+
+	tests := []struct {
+		name     string
+		probe    string
+		expected bool
+	}{
+		{
+			name:     "updated by the other MetricsService",
+			probe:    "db",
+			expected: true,
+		},
+		{
+			name:     "stale",
+			probe:    "cache",
+			expected: false,
+		},
+		{
+			name:     "updated",
+			probe:    "queue",
+			expected: true,
+		},
+	}
+
+	probes := statusSeries(t, reg)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if probes[tt.probe] != tt.expected {
+				t.Fatalf("expected the metrics of %q to be kept: %t, got %v", tt.probe, tt.expected, probes)
+			}
+		})
+	}
+}
+
+func TestServiceIgnoresTheProbesRemovedDuringTheExecution(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metricsService := NewPrometheusMetricsServiceWithHandler("test", reg, promhttp.HandlerOpts{})
+
+	probeStore := NewInMemoryProbeStore()
+	err := probeStore.Add(
+		Probe{
+			Name: "db",
+			Kind: ReadinessProbeKind,
+			CheckFn: func(context.Context) error {
+				return probeStore.Delete("db")
+			},
+		},
+		Probe{
+			Name:    "cache",
+			Kind:    ReadinessProbeKind,
+			CheckFn: func(context.Context) error { return nil },
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := NewService(probeStore, metricsService)
+
+	executionResults, err := service.ExecuteAllProbes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(executionResults) != 2 {
+		t.Fatalf("expected the results of both probes to be returned, got %v", executionResults)
+	}
+
+	if probes := statusSeries(t, reg); probes["db"] || !probes["cache"] {
+		t.Fatalf("expected only the metrics of the registered probe, got %v", probes)
+	}
+
+	if lastResults := service.LastResults(); len(lastResults) != 1 || lastResults[0].Probe.Name != "cache" {
+		t.Fatalf("expected only the result of the registered probe, got %v", lastResults)
+	}
+
+	if history := service.History(); len(history["db"]) != 0 {
+		t.Fatalf("expected no history of the removed probe, got %v", history["db"])
+	}
+}
+
+func TestPrometheusMetricsServiceCountsTheExecutedErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		override *Override
+		cached   bool
+		expected float64
+	}{
+		{
+			name:     "executed",
+			expected: 1,
+		},
+		{
+			name:     "muted",
+			override: &Override{Mode: MutedOverride},
+			expected: 1,
+		},
+		{
+			name:   "cached",
+			cached: true,
+		},
+		{
+			name:     "forced",
+			override: &Override{Mode: ForcedOverride, Status: UnhealthyStatus},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			metricsService := NewPrometheusMetricsServiceWithHandler("test", reg, promhttp.HandlerOpts{})
+
+			metricsService.UpdateGauge(ExecutionResult{
+				Probe:     Probe{Name: "db", Kind: ReadinessProbeKind, Health: UnhealthyStatus},
+				Err:       &CheckError{Code: ConnectionErrorCode, Message: "could not connect"},
+				StartedAt: time.Now(),
+				Cached:    tt.cached,
+				Override:  tt.override,
+			})
+
+			families, err := reg.Gather()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var errors float64
+			for _, f := range families {
+				if f.GetName() == "test_healthcheck_check_errors_total" {
+					for _, m := range f.GetMetric() {
+						errors += m.GetCounter().GetValue()
+					}
+				}
+			}
+
+			if errors != tt.expected {
+				t.Fatalf("expected %g errors, got %g", tt.expected, errors)
+			}
+		})
+	}
+}
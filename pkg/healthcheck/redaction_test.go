@@ -0,0 +1,78 @@
+package healthcheck
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDefaultRedactionRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{
+			name:     "url userinfo",
+			message:  "dial postgres://user:secret@db:5432/app",
+			expected: "dial postgres://[REDACTED]@db:5432/app",
+		},
+		{
+			name:     "url userinfo with @ in the password",
+			message:  "dial postgres://user:p@ss@db:5432/app failed",
+			expected: "dial postgres://[REDACTED]@db:5432/app failed",
+		},
+		{
+			name:     "dsn password",
+			message:  "connect host=db password=secret sslmode=disable",
+			expected: "connect host=db password=[REDACTED] sslmode=disable",
+		},
+		{
+			name:     "bearer token",
+			message:  "unauthorized: Bearer abc.def-ghi",
+			expected: "unauthorized: Bearer [REDACTED]",
+		},
+		{
+			name:     "ipv4 address",
+			message:  "dial tcp 10.0.0.1:5432: connect: connection refused",
+			expected: "dial tcp [REDACTED]:5432: connect: connection refused",
+		},
+		{
+			name:     "ipv6 address",
+			message:  "dial tcp [fe80::1]:5432 and 2001:db8::ff00:42:8329",
+			expected: "dial tcp [[REDACTED]]:5432 and [REDACTED]",
+		},
+		{
+			name:     "not an ipv6 address",
+			message:  "std::string at 10:30:00 on [::]:80",
+			expected: "std::string at 10:30:00 on [::]:80",
+		},
+	}
+
+	redactor := NewRedactor(DefaultRedactionRules()...)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := errors.New(tt.message)
+			r := redactor.Redact(ExecutionResult{Err: err})
+
+			if r.Err.Error() != tt.expected {
+				t.Fatalf("expected %q, got %q", tt.expected, r.Err.Error())
+			}
+
+			if !errors.Is(r.Err, err) {
+				t.Fatal("expected the redacted error to wrap the original one")
+			}
+		})
+	}
+}
+
+func TestRedactorHidesTheDetails(t *testing.T) {
+	r := NewRedactor(DefaultRedactionRules()...).Redact(ExecutionResult{
+		Probe: Probe{HideDetails: true},
+		Err:   &CheckError{Code: ConnectionErrorCode, Message: "could not connect to db-1"},
+	})
+
+	if r.Err.Error() != ErrCheckFailed.Error() || ErrorCodeOf(r.Err) != ConnectionErrorCode {
+		t.Fatalf("expected a generic message with the error code, got %q (%s)", r.Err, ErrorCodeOf(r.Err))
+	}
+}
@@ -2,9 +2,12 @@ package healthcheck
 
 import (
 	"context"
+	"log"
 	"sync"
+	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var ErrCheckFailed = errors.New("probe check failed")
@@ -17,86 +20,520 @@ type Service interface {
 
 	// ExecuteProbesByKind uses ExecuteProbes on all the probes of this ProbeKind.
 	ExecuteProbesByKind(ctx context.Context, kind ProbeKind) ([]ExecutionResult, error)
+
+	// ExecuteProbesByName uses ExecuteProbes on the probes with these names.
+	// The names that are not in the ProbeStore are ignored.
+	ExecuteProbesByName(ctx context.Context, names ...string) ([]ExecutionResult, error)
+
+	// LastResults returns the last ExecutionResult of every probe in the ProbeStore, without executing them.
+	// The probes that were never executed have an empty health status.
+	LastResults() []ExecutionResult
+
+	// SetOverride disables, mutes, or forces the status of a probe, until the override expires or is cleared.
+	// It returns ErrProbeNotFound if the probe is not in the ProbeStore.
+	SetOverride(name string, o Override) error
+
+	// ClearOverride removes the override of a probe, if any.
+	// It returns ErrProbeNotFound if the probe is not in the ProbeStore.
+	ClearOverride(name string) error
+
+	// Overrides returns the overrides that didn't expire, by probe name.
+	Overrides() map[string]Override
+
+	// History returns the recent results of the probes, from the oldest to the newest.
+	// If no names are provided, the history of all the probes is returned.
+	History(names ...string) map[string][]HistoryEntry
+
+	// Subscribe returns a Subscription to the events of the probes,
+	// with a buffer of bufferSize events.
+	Subscribe(bufferSize int) Subscription
+
+	// SubscribeFunc calls fn for each event, in a separate goroutine.
+	// The events are buffered as for Subscribe.
+	SubscribeFunc(bufferSize int, fn func(Event)) Subscription
 }
 
 type service struct {
 	metricsService MetricsService
 	probeStore     ProbeStore
+	redactor       Redactor
+	history        *resultHistory
+	flapDetector   *flapDetector
+	eventBroker    *eventBroker
+	statusTracker  *statusTracker
+	lastResults    *lastResults
+	overrides      *overrideStore
+	stateStore     StateStore
+	stateMu        *sync.Mutex
+	recordMu       *sync.RWMutex
+	stateSchedule  *stateSchedule
+	semaphore      chan struct{}
+	minInterval    time.Duration
+	flights        *flightGroup
+	resultOrder    ResultOrder
+	tracer         trace.Tracer
+}
+
+// ServiceOption configures the optional features of the Service.
+type ServiceOption func(s *service)
+
+// WithHistory configures how many results are kept for each probe.
+//
+// By default, the DefaultHistoryOptions are used.
+func WithHistory(opts HistoryOptions) ServiceOption {
+	return func(s *service) {
+		s.history = newResultHistory(opts)
+	}
+}
+
+// WithFlappingDetection configures the detection of the probes that change their status too often.
+//
+// By default, the DefaultFlappingOptions are used.
+func WithFlappingDetection(opts FlappingOptions) ServiceOption {
+	return func(s *service) {
+		s.flapDetector = newFlapDetector(opts)
+	}
+}
+
+// WithStateStore persists the last results, the history, the flap detection state, and the overrides of the probes,
+// so they survive restarts.
+//
+// The State is loaded when the Service is created. It is saved when the status, the override, or the flapping
+// of a probe changes, and otherwise at most every 30 seconds, so the history is not written after every execution.
+func WithStateStore(stateStore StateStore) ServiceOption {
+	return func(s *service) {
+		s.stateStore = stateStore
+	}
+}
+
+// WithConcurrency limits the number of probes executed at the same time,
+// and how often the probes are executed.
+//
+// By default, there are no limits.
+func WithConcurrency(opts ConcurrencyOptions) ServiceOption {
+	return func(s *service) {
+		s.semaphore = nil
+		if opts.MaxConcurrency > 0 {
+			s.semaphore = make(chan struct{}, opts.MaxConcurrency)
+		}
+
+		s.minInterval = opts.MinInterval
+
+		s.flights = nil
+		if opts.Coalesce {
+			s.flights = newFlightGroup(opts.CoalesceTimeout)
+		}
+	}
+}
+
+// WithResultOrder sets the order of the ExecutionResult(s) returned by the Service.
+//
+// By default, the results are ordered by kind, then by name (OrderByKindAndName).
+func WithResultOrder(order ResultOrder) ServiceOption {
+	return func(s *service) {
+		s.resultOrder = order
+	}
+}
+
+// WithRedactor sets the Redactor applied to every ExecutionResult.
+//
+// By default, the DefaultRedactionRules are applied.
+func WithRedactor(r Redactor) ServiceOption {
+	return func(s *service) {
+		s.redactor = r
+	}
 }
 
 func (s service) ExecuteAllProbes(ctx context.Context) ([]ExecutionResult, error) {
-	probes := s.probeStore.GetAll()
+	fn := func(ctx context.Context) ([]ExecutionResult, error) {
+		probes := s.probeStore.GetAll()
 
-	return s.ExecuteProbes(ctx, probes...)
+		return s.execute(ctx, allProbesScope, probes)
+	}
+
+	if s.flights == nil {
+		return fn(ctx)
+	}
+
+	return s.flights.do(ctx, "all", fn)
 }
 
 func (s service) ExecuteProbes(ctx context.Context, probes ...Probe) ([]ExecutionResult, error) {
+	return s.execute(ctx, selectedProbesScope, probes)
+}
+
+// execute runs the probes, and records their results. The scope identifies the execution in its span.
+func (s service) execute(ctx context.Context, scope string, probes []Probe) ([]ExecutionResult, error) {
+	registered := make(map[string]bool, len(probes))
+	for _, p := range probes {
+		_, registered[p.Name] = s.probeStore.Get(p.Name)
+	}
+
+	ctx, span := s.startExecutionSpan(ctx, scope, probes)
 	executionResults := s.executeProbes(ctx, probes)
+	endExecutionSpan(span, executionResults)
 
-	go s.metricsService.UpdateGauge(executionResults...)
+	hasChanged := s.record(executionResults, registered)
+
+	if s.stateStore != nil && s.stateSchedule.isDue(hasChanged) {
+		go s.saveState()
+	}
 
 	return executionResults, nil
 }
 
+// record keeps the results, publishes the events, and updates the metrics,
+// and it returns whether the status or the flapping of a probe changed.
+//
+// The results of the probes removed from the ProbeStore during the execution are ignored,
+// and deleteRemovedProbes waits for the results being recorded, so the removed probes are not recorded again.
+func (s service) record(executionResults []ExecutionResult, registered map[string]bool) bool {
+	s.recordMu.RLock()
+	defer s.recordMu.RUnlock()
+
+	indexes := make([]int, 0, len(executionResults))
+	recorded := make([]ExecutionResult, 0, len(executionResults))
+	for i, r := range executionResults {
+		if _, ok := s.probeStore.Get(r.Probe.Name); registered[r.Probe.Name] && !ok {
+			continue
+		}
+
+		indexes = append(indexes, i)
+		recorded = append(recorded, r)
+	}
+
+	s.history.record(recorded...)
+	hasFlappingChanged := s.flapDetector.apply(recorded)
+	for j, i := range indexes {
+		executionResults[i] = recorded[j]
+	}
+
+	s.lastResults.record(recorded...)
+
+	events := s.statusTracker.update(recorded)
+	s.eventBroker.publish(events...)
+
+	s.metricsService.UpdateGauge(recorded...)
+
+	return len(events) > 0 || hasFlappingChanged
+}
+
 func (s service) executeProbes(ctx context.Context, probes []Probe) []ExecutionResult {
 	var wg sync.WaitGroup
-	c := make(chan ExecutionResult)
+	executionResults := make([]ExecutionResult, len(probes))
 
 	wg.Add(len(probes))
 
-	go func() {
-		wg.Wait()
-		close(c)
-	}()
-
-	for _, p := range probes {
-		go func(p Probe) {
+	for i, p := range probes {
+		go func(i int, p Probe) {
 			defer wg.Done()
-			r := ExecutionResult{
-				Probe: p,
-			}
 
-			err := p.Execute(ctx)
-			if err != nil {
-				r.Err = err
-				r.Probe.Health = UnhealthyStatus
-			} else {
-				r.Probe.Health = HealthyStatus
+			ctx, span := s.startProbeSpan(ctx, p)
+			executionResults[i] = s.redact(s.executeProbe(ctx, p))
+			endProbeSpan(span, executionResults[i])
+		}(i, p)
+	}
+
+	wg.Wait()
+
+	s.resultOrder.sortResults(executionResults)
+
+	return executionResults
+}
+
+// executeProbe executes the ProbeCheckFn of the Probe, unless it is disabled or its status is forced.
+func (s service) executeProbe(ctx context.Context, p Probe) ExecutionResult {
+	r := ExecutionResult{
+		Probe:     p,
+		StartedAt: time.Now(),
+	}
+
+	o, ok := s.overrides.get(p.Name)
+	if ok {
+		r.Override = &o
+
+		switch o.Mode {
+		case DisabledOverride:
+			r.Probe.Health = SkippedStatus
+			return r
+		case ForcedOverride:
+			r.Probe.Health = o.Status
+			if o.Status == UnhealthyStatus {
+				r.Err = &CheckError{Code: ForcedErrorCode, Message: "status forced: " + o.Reason}
 			}
 
-			c <- r
-		}(p)
+			return r
+		}
 	}
 
-	executionResults := make([]ExecutionResult, 0, len(probes))
-	for executionResult := range c {
-		executionResults = append(executionResults, executionResult)
+	if cached, ok := s.cachedResult(p); ok {
+		return cached
 	}
 
-	return executionResults
+	if s.semaphore != nil {
+		select {
+		case s.semaphore <- struct{}{}:
+			defer func() { <-s.semaphore }()
+		case <-ctx.Done():
+			r.Err = ctx.Err()
+			r.Probe.Health = UnhealthyStatus
+
+			return r
+		}
+
+		// the probe may have been executed by another caller while waiting
+		if cached, ok := s.cachedResult(p); ok {
+			return cached
+		}
+
+		r.StartedAt = time.Now()
+	}
+
+	probeCtx, childResults := withChildResultsRecorder(ctx)
+	err := p.Execute(probeCtx)
+	r.Duration = time.Since(r.StartedAt)
+	r.Children = childResults.list()
+	if err != nil {
+		r.Err = err
+		r.Probe.Health = UnhealthyStatus
+	} else {
+		r.Probe.Health = HealthyStatus
+	}
+
+	return r
+}
+
+// cachedResult returns the last result of the probe, if it was executed within its minimum interval.
+func (s service) cachedResult(p Probe) (ExecutionResult, bool) {
+	minInterval := p.MinInterval
+	if minInterval == 0 {
+		minInterval = s.minInterval
+	}
+
+	if minInterval <= 0 {
+		return ExecutionResult{}, false
+	}
+
+	r, ok := s.lastResults.get(p.Name)
+	if !ok || r.Override != nil || time.Since(r.StartedAt) >= minInterval {
+		return ExecutionResult{}, false
+	}
+
+	health := r.Probe.Health
+	r.Probe = p
+	r.Probe.Health = health
+	r.Cached = true
+
+	return r, true
+}
+
+// redact applies the Redactor to the result and to its children.
+func (s service) redact(r ExecutionResult) ExecutionResult {
+	r = s.redactor.Redact(r)
+	for i := range r.Children {
+		r.Children[i] = s.redact(r.Children[i])
+	}
+
+	return r
 }
 
 func (s service) ExecuteProbesByKind(ctx context.Context, kind ProbeKind) ([]ExecutionResult, error) {
-	var probes []Probe
+	fn := func(ctx context.Context) ([]ExecutionResult, error) {
+		var probes []Probe
 
-	if kind == CustomProbeKind {
-		probes = s.probeStore.GetAll()
-	} else {
-		probes = s.probeStore.GetByKind(kind)
+		if kind == CustomProbeKind {
+			probes = s.probeStore.GetAll()
+		} else {
+			probes = s.probeStore.GetByKind(kind)
+		}
+
+		executionResults, err := s.execute(ctx, string(kind), probes)
+		if err != nil {
+			return nil, err
+		}
+
+		return executionResults, nil
+	}
+
+	if s.flights == nil {
+		return fn(ctx)
+	}
+
+	return s.flights.do(ctx, "kind:"+string(kind), fn)
+}
+
+func (s service) ExecuteProbesByName(ctx context.Context, names ...string) ([]ExecutionResult, error) {
+	probes := make([]Probe, 0, len(names))
+	for _, name := range names {
+		p, ok := s.probeStore.Get(name)
+		if !ok {
+			continue
+		}
+
+		probes = append(probes, p)
 	}
 
-	executionResults, err := s.ExecuteProbes(ctx, probes...)
+	return s.ExecuteProbes(ctx, probes...)
+}
+
+func (s service) LastResults() []ExecutionResult {
+	probes := s.probeStore.GetAll()
+
+	executionResults := make([]ExecutionResult, 0, len(probes))
+	for _, p := range probes {
+		r, ok := s.lastResults.get(p.Name)
+		if !ok {
+			r = ExecutionResult{Probe: p}
+		}
+
+		executionResults = append(executionResults, r)
+	}
+
+	s.resultOrder.sortResults(executionResults)
+
+	return executionResults
+}
+
+func (s service) SetOverride(name string, o Override) error {
+	err := o.validate()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return executionResults, nil
+	if _, ok := s.probeStore.Get(name); !ok {
+		return errors.Wrapf(ErrProbeNotFound, "probe %q", name)
+	}
+
+	s.overrides.set(name, o)
+	s.saveState()
+
+	return nil
 }
 
-func NewService(probeStore ProbeStore, metricsService MetricsService) Service {
+func (s service) ClearOverride(name string) error {
+	if _, ok := s.probeStore.Get(name); !ok {
+		return errors.Wrapf(ErrProbeNotFound, "probe %q", name)
+	}
+
+	s.overrides.delete(name)
+	s.saveState()
+
+	return nil
+}
+
+func (s service) Overrides() map[string]Override {
+	return s.overrides.list()
+}
+
+func (s service) History(names ...string) map[string][]HistoryEntry {
+	return s.history.get(names...)
+}
+
+func (s service) Subscribe(bufferSize int) Subscription {
+	return s.eventBroker.subscribe(bufferSize)
+}
+
+func (s service) SubscribeFunc(bufferSize int, fn func(Event)) Subscription {
+	sub := s.eventBroker.subscribe(bufferSize)
+
+	go func() {
+		for e := range sub.Events() {
+			fn(e)
+		}
+	}()
+
+	return sub
+}
+
+func (s service) onProbeStoreChange(added, removed []Probe) {
+	s.deleteRemovedProbes(added, removed)
+
+	if len(removed) > 0 {
+		s.saveState()
+	}
+}
+
+// deleteRemovedProbes deletes the results, the state, and the metrics of the removed probes,
+// and publishes the events of the added and removed probes.
+func (s service) deleteRemovedProbes(added, removed []Probe) {
+	s.recordMu.Lock()
+	defer s.recordMu.Unlock()
+
+	now := time.Now()
+	events := make([]Event, 0, len(added)+len(removed))
+
+	for _, p := range added {
+		events = append(events, Event{
+			Type:  ProbeAddedEvent,
+			Time:  now,
+			Kind:  p.Kind,
+			Probe: p,
+		})
+	}
+
+	for _, p := range removed {
+		events = append(events, Event{
+			Type:  ProbeRemovedEvent,
+			Time:  now,
+			Kind:  p.Kind,
+			Probe: p,
+		})
+	}
+
+	names := make([]string, 0, len(removed))
+	for _, p := range removed {
+		names = append(names, p.Name)
+	}
+	s.history.delete(names...)
+	s.flapDetector.delete(names...)
+	s.lastResults.delete(names...)
+	s.overrides.delete(names...)
+
+	if d, ok := s.metricsService.(ProbeMetricsDeleter); ok && len(removed) > 0 {
+		d.DeleteProbeMetrics(removed...)
+	}
+
+	events = append(events, s.statusTracker.remove(removed...)...)
+	s.eventBroker.publish(events...)
+}
+
+func NewService(probeStore ProbeStore, metricsService MetricsService, opts ...ServiceOption) Service {
 	s := &service{
 		metricsService: metricsService,
 		probeStore:     probeStore,
+		redactor:       NewRedactor(DefaultRedactionRules()...),
+		history:        newResultHistory(DefaultHistoryOptions()),
+		flapDetector:   newFlapDetector(DefaultFlappingOptions()),
+		eventBroker:    newEventBroker(),
+		statusTracker:  newStatusTracker(),
+		lastResults:    newLastResults(),
+		overrides:      newOverrideStore(),
+		stateMu:        &sync.Mutex{},
+		recordMu:       &sync.RWMutex{},
+		stateSchedule:  &stateSchedule{},
+		resultOrder:    OrderByKindAndName,
+		tracer:         trace.NewNoopTracerProvider().Tracer(tracerName),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if s.stateStore != nil {
+		state, err := s.stateStore.Load()
+		if err != nil {
+			log.Printf("could not load the health check state: %s\n", err)
+		}
+
+		s.restoreState(state)
+	}
+
+	if w, ok := probeStore.(probeStoreWatcher); ok {
+		w.watch(s.onProbeStoreChange)
+	}
+
+	if b, ok := metricsService.(serviceBinder); ok {
+		b.bindService(s)
 	}
 
 	return s
@@ -0,0 +1,244 @@
+package healthcheck
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// State is the persisted state of the Service, which survives restarts (see WithStateStore).
+//
+// The ProbeCheckFn(s) are not persisted: the probes are re-bound by name when your code adds them to the ProbeStore.
+type State struct {
+	SavedAt time.Time    `json:"saved_at"`
+	Probes  []ProbeState `json:"probes"`
+}
+
+type ProbeState struct {
+	Name        string    `json:"name"`
+	Kind        ProbeKind `json:"kind"`
+	HideDetails bool      `json:"hide_details,omitempty"`
+
+	LastResult *HistoryEntry       `json:"last_result,omitempty"`
+	Flapping   bool                `json:"flapping,omitempty"`
+	Override   *Override           `json:"override,omitempty"`
+	History    []HistoryEntry      `json:"history,omitempty"`
+	FlapWindow []ProbeHealthStatus `json:"flap_window,omitempty"`
+}
+
+// StateStore persists the State of the Service.
+type StateStore interface {
+	Load() (State, error)
+	Save(state State) error
+}
+
+type fileStateStore struct {
+	path string
+}
+
+// Load returns an empty State if the file doesn't exist.
+func (s fileStateStore) Load() (State, error) {
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return State{}, nil
+		}
+
+		return State{}, errors.Wrap(err, "could not read the state file")
+	}
+
+	var state State
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		return State{}, errors.Wrap(err, "could not parse the state file")
+	}
+
+	return state, nil
+}
+
+// Save replaces the file atomically, so a crash never leaves a partially written state.
+func (s fileStateStore) Save(state State) error {
+	content, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "could not create the state file")
+	}
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrap(err, "could not write the state file")
+	}
+
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// NewFileStateStore creates a StateStore that keeps the State as a JSON file.
+func NewFileStateStore(path string) StateStore {
+	return fileStateStore{
+		path: path,
+	}
+}
+
+// ReadStateFile reads the last known State from a file written by the file StateStore,
+// e.g. to inspect it offline while the app is down.
+func ReadStateFile(path string) (State, error) {
+	return NewFileStateStore(path).Load()
+}
+
+// snapshotState collects the state of the probes in the ProbeStore,
+// and of the probes restored from a previous State that were not added (yet) to the ProbeStore.
+func (s service) snapshotState() State {
+	// only the probes in the ProbeStore are persisted,
+	// so the state of a removed probe is not restored onto a new probe with the same name
+	probes := map[string]*ProbeState{}
+	for _, probe := range s.probeStore.GetAll() {
+		probes[probe.Name] = &ProbeState{
+			Name:        probe.Name,
+			Kind:        probe.Kind,
+			HideDetails: probe.HideDetails,
+		}
+	}
+
+	for _, r := range s.lastResults.list() {
+		p, ok := probes[r.Probe.Name]
+		if !ok {
+			continue
+		}
+
+		p.Flapping = r.Flapping
+
+		lastResult := newHistoryEntry(r, 0)
+		p.LastResult = &lastResult
+	}
+
+	for name, entries := range s.history.get() {
+		if p, ok := probes[name]; ok {
+			p.History = entries
+		}
+	}
+
+	for name, statuses := range s.flapDetector.windows() {
+		if p, ok := probes[name]; ok {
+			p.FlapWindow = statuses
+		}
+	}
+
+	for name, o := range s.overrides.list() {
+		o := o
+		if p, ok := probes[name]; ok {
+			p.Override = &o
+		}
+	}
+
+	state := State{
+		SavedAt: time.Now(),
+		Probes:  make([]ProbeState, 0, len(probes)),
+	}
+
+	for _, p := range probes {
+		state.Probes = append(state.Probes, *p)
+	}
+
+	sort.Slice(state.Probes, func(i, j int) bool { return state.Probes[i].Name < state.Probes[j].Name })
+
+	return state
+}
+
+func (s service) restoreState(state State) {
+	for _, p := range state.Probes {
+		if p.LastResult != nil {
+			r := ExecutionResult{
+				Probe: Probe{
+					Kind:        p.Kind,
+					Name:        p.Name,
+					Health:      p.LastResult.Status,
+					HideDetails: p.HideDetails,
+				},
+				StartedAt: p.LastResult.Time,
+				Duration:  p.LastResult.Duration,
+				Flapping:  p.Flapping,
+				Override:  p.Override,
+			}
+
+			if p.LastResult.Error != "" {
+				r.Err = errors.New(p.LastResult.Error)
+			}
+
+			s.lastResults.record(r)
+
+			// the status transitions are detected from the restored status, so a restart doesn't emit events
+			s.statusTracker.update([]ExecutionResult{r})
+		}
+
+		if len(p.History) > 0 {
+			s.history.restore(p.Name, p.History)
+		}
+
+		if len(p.FlapWindow) > 0 {
+			s.flapDetector.restore(p.Name, p.FlapWindow, p.Flapping)
+		}
+
+		if p.Override != nil && !p.Override.isExpired(time.Now()) {
+			s.overrides.set(p.Name, *p.Override)
+		}
+	}
+}
+
+// stateSaveInterval is the maximum interval between two saves of the state after the executions of the probes,
+// when no status changed.
+const stateSaveInterval = 30 * time.Second
+
+type stateSchedule struct {
+	mu        sync.Mutex
+	lastSaved time.Time
+}
+
+// isDue reports whether the state must be saved after an execution, and records the save if so.
+func (sch *stateSchedule) isDue(hasChanged bool) bool {
+	sch.mu.Lock()
+	defer sch.mu.Unlock()
+
+	now := time.Now()
+	if !hasChanged && now.Sub(sch.lastSaved) < stateSaveInterval {
+		return false
+	}
+
+	sch.lastSaved = now
+
+	return true
+}
+
+// saveState persists the state, if the Service has a StateStore.
+func (s service) saveState() {
+	if s.stateStore == nil {
+		return
+	}
+
+	s.stateMu.Lock()
+	defer s.stateMu.Unlock()
+
+	err := s.stateStore.Save(s.snapshotState())
+	if err != nil {
+		log.Printf("could not save the health check state: %s\n", err)
+	}
+}
@@ -0,0 +1,59 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// memoryStateStore loads the initial State, and sends the saved ones to the channel.
+type memoryStateStore struct {
+	initial State
+	saved   chan State
+}
+
+func (s memoryStateStore) Load() (State, error) { return s.initial, nil }
+
+func (s memoryStateStore) Save(state State) error {
+	s.saved <- state
+	return nil
+}
+
+func TestServiceOnlySavesTheStateOfRegisteredProbes(t *testing.T) {
+	lastResult := HistoryEntry{Time: time.Now(), Status: UnhealthyStatus, Error: "connection refused"}
+	stateStore := memoryStateStore{
+		initial: State{Probes: []ProbeState{
+			{Name: "db", Kind: ReadinessProbeKind, LastResult: &lastResult},
+			{Name: "removed", Kind: ReadinessProbeKind, LastResult: &lastResult},
+		}},
+		saved: make(chan State, 10),
+	}
+
+	probeStore := NewInMemoryProbeStore()
+	service := NewService(probeStore, NewNoOpMetricsService(), WithStateStore(stateStore))
+
+	err := probeStore.Add(Probe{Name: "db", Kind: ReadinessProbeKind, CheckFn: func(context.Context) error { return nil }})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = service.ExecuteAllProbes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state State
+	select {
+	case state = <-stateStore.saved:
+	case <-time.After(time.Second):
+		t.Fatal("expected the state to be saved")
+	}
+
+	if len(state.Probes) != 1 || state.Probes[0].Name != "db" {
+		t.Fatalf("expected only the state of the registered probe, got %+v", state.Probes)
+	}
+
+	if state.Probes[0].LastResult == nil || state.Probes[0].LastResult.Status != HealthyStatus {
+		t.Fatalf("expected the last result of the execution, got %+v", state.Probes[0].LastResult)
+	}
+}
@@ -0,0 +1,228 @@
+package healthcheck
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// StatsDFormat is the line format of the metrics.
+type StatsDFormat string
+
+const (
+	// StatsDFormatPlain adds the kind and the name of the probe to the metric name (e.g. 'healthcheck.status.readiness.db').
+	StatsDFormatPlain StatsDFormat = "statsd"
+
+	// DogStatsDFormat adds the kind and the name of the probe as tags (e.g. 'healthcheck.status:1|g|#kind:readiness,probe:db').
+	DogStatsDFormat StatsDFormat = "dogstatsd"
+)
+
+type StatsDOptions struct {
+	// Address of the StatsD server or agent (e.g. '127.0.0.1:8125').
+	Address string
+	Format  StatsDFormat
+
+	// Prefix is added to the name of all the metrics (e.g. 'my_app.healthcheck.').
+	Prefix string
+
+	// SampleRate is the rate at which the counters and the timings are sent, between 0 and 1.
+	// The gauges are always sent.
+	SampleRate float64
+
+	// Tags are added to all the metrics (e.g. 'env:prod'), in the DogStatsD format only.
+	Tags []string
+
+	// MaxPacketSize is the maximum size of a UDP packet.
+	// The metrics of an update are batched in as few packets as possible.
+	MaxPacketSize int
+}
+
+func DefaultStatsDOptions(address string) StatsDOptions {
+	return StatsDOptions{
+		Address:       address,
+		Format:        DogStatsDFormat,
+		Prefix:        "healthcheck.",
+		SampleRate:    1,
+		MaxPacketSize: 1432,
+	}
+}
+
+// statsdMetricsService sends, for every ExecutionResult:
+//   - the gauge 'status' (0=healthy, 1=unhealthy);
+//   - the timing 'duration', in milliseconds;
+//   - the counter 'results', with the status of the probe, and the error code of the unhealthy ones.
+//
+// The results that were not executed (see ExecutionResult.Executed) only update the gauge.
+type statsdMetricsService struct {
+	opts StatsDOptions
+
+	mu   sync.Mutex
+	conn net.Conn
+	buf  bytes.Buffer
+}
+
+// GetHandler returns a handler that responds with 404 Not Found, since the metrics are pushed to the server.
+func (s *statsdMetricsService) GetHandler() http.Handler {
+	return http.NotFoundHandler()
+}
+
+type statsdMetric struct {
+	name       string
+	value      string
+	metricType string
+	isSampled  bool
+
+	// tags are added as they are in the DogStatsD format, and their values are added to the name otherwise.
+	tags []string
+}
+
+func (s *statsdMetricsService) UpdateGauge(executionResults ...ExecutionResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, e := range executionResults {
+		p := e.Probe
+
+		switch p.Health {
+		case HealthyStatus:
+			s.write(p, statsdMetric{name: "status", value: "0", metricType: "g"})
+		case UnhealthyStatus:
+			s.write(p, statsdMetric{name: "status", value: "1", metricType: "g"})
+		}
+
+		if !e.Executed() {
+			continue
+		}
+
+		duration := float64(e.Duration.Microseconds()) / 1000
+		s.write(p, statsdMetric{name: "duration", value: fmt.Sprintf("%g", duration), metricType: "ms", isSampled: true})
+
+		results := statsdMetric{name: "results", value: "1", metricType: "c", isSampled: true, tags: []string{"status:" + string(p.Health)}}
+		if e.Err != nil {
+			results.tags = append(results.tags, "code:"+string(ErrorCodeOf(e.Err)))
+		}
+		s.write(p, results)
+	}
+
+	s.flush()
+}
+
+// write adds the metric to the buffer, and flushes it first if the packet would be too large.
+// It must be called with the lock held.
+func (s *statsdMetricsService) write(p Probe, m statsdMetric) {
+	isSampled := m.isSampled && s.opts.SampleRate < 1
+	if isSampled && rand.Float64() >= s.opts.SampleRate {
+		return
+	}
+
+	tags := append([]string{"kind:" + string(p.Kind), "probe:" + p.Name}, m.tags...)
+
+	var line strings.Builder
+	line.WriteString(s.opts.Prefix)
+	line.WriteString(m.name)
+
+	if s.opts.Format == StatsDFormatPlain {
+		for _, tag := range tags {
+			_, value, _ := strings.Cut(tag, ":")
+			line.WriteString("." + sanitizeStatsDName(value))
+		}
+	}
+
+	line.WriteString(":" + m.value + "|" + m.metricType)
+
+	if isSampled {
+		line.WriteString(fmt.Sprintf("|@%g", s.opts.SampleRate))
+	}
+
+	if s.opts.Format == DogStatsDFormat {
+		for i := range tags {
+			tags[i] = sanitizeStatsDTag(tags[i])
+		}
+
+		line.WriteString("|#" + strings.Join(append(tags, s.opts.Tags...), ","))
+	}
+
+	if s.buf.Len() > 0 && s.buf.Len()+1+line.Len() > s.opts.MaxPacketSize {
+		s.flush()
+	}
+
+	if s.buf.Len() > 0 {
+		s.buf.WriteByte('\n')
+	}
+	s.buf.WriteString(line.String())
+}
+
+// flush must be called with the lock held.
+func (s *statsdMetricsService) flush() {
+	if s.buf.Len() == 0 {
+		return
+	}
+
+	_, err := s.conn.Write(s.buf.Bytes())
+	if err != nil {
+		log.Printf("could not send the metrics: %s\n", err)
+	}
+
+	s.buf.Reset()
+}
+
+func sanitizeStatsDName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+func sanitizeStatsDTag(value string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '|', '#', '\n', '\r':
+			return '_'
+		default:
+			return r
+		}
+	}, value)
+}
+
+// NewStatsDMetricsService sends the metrics over UDP, in the StatsD or DogStatsD format.
+func NewStatsDMetricsService(opts StatsDOptions) (MetricsService, error) {
+	defaults := DefaultStatsDOptions(opts.Address)
+	if opts.Format == "" {
+		opts.Format = defaults.Format
+	}
+
+	if opts.SampleRate <= 0 || opts.SampleRate > 1 {
+		opts.SampleRate = defaults.SampleRate
+	}
+
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = defaults.MaxPacketSize
+	}
+
+	if opts.Format != StatsDFormatPlain && opts.Format != DogStatsDFormat {
+		return nil, errors.Errorf("unknown statsd format %q", opts.Format)
+	}
+
+	conn, err := net.Dial("udp", opts.Address)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to the statsd server")
+	}
+
+	s := &statsdMetricsService{
+		opts: opts,
+		conn: conn,
+	}
+
+	return s, nil
+}
@@ -0,0 +1,194 @@
+package healthcheck
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listenStatsD(t *testing.T) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+// readStatsDPackets reads the packets until none is received for a while.
+func readStatsDPackets(t *testing.T, conn *net.UDPConn) []string {
+	t.Helper()
+
+	var packets []string
+	buf := make([]byte, 65536)
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return packets
+		}
+
+		packets = append(packets, string(buf[:n]))
+	}
+}
+
+func statsDTestResults() []ExecutionResult {
+	return []ExecutionResult{
+		{
+			Probe:     Probe{Name: "cache", Kind: ReadinessProbeKind, Health: HealthyStatus},
+			StartedAt: time.Now(),
+			Duration:  1500 * time.Microsecond,
+		},
+		{
+			Probe:     Probe{Name: "db", Kind: ReadinessProbeKind, Health: UnhealthyStatus},
+			StartedAt: time.Now(),
+			Duration:  2 * time.Millisecond,
+			Err:       &CheckError{Code: ConnectionErrorCode, Message: "could not connect"},
+		},
+	}
+}
+
+func assertStatsDLines(t *testing.T, packets []string, expected []string) {
+	t.Helper()
+
+	got := strings.Split(strings.Join(packets, "\n"), "\n")
+	if strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("expected the lines:\n%s\ngot:\n%s", strings.Join(expected, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestStatsDMetricsService(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   StatsDFormat
+		prefix   string
+		tags     []string
+		results  func() []ExecutionResult
+		expected []string
+	}{
+		{
+			name:    "dogstatsd format",
+			tags:    []string{"env:prod"},
+			results: statsDTestResults,
+			expected: []string{
+				"healthcheck.status:0|g|#kind:readiness,probe:cache,env:prod",
+				"healthcheck.duration:1.5|ms|#kind:readiness,probe:cache,env:prod",
+				"healthcheck.results:1|c|#kind:readiness,probe:cache,status:healthy,env:prod",
+				"healthcheck.status:1|g|#kind:readiness,probe:db,env:prod",
+				"healthcheck.duration:2|ms|#kind:readiness,probe:db,env:prod",
+				"healthcheck.results:1|c|#kind:readiness,probe:db,status:unhealthy,code:" + string(ConnectionErrorCode) + ",env:prod",
+			},
+		},
+		{
+			name:   "plain format",
+			format: StatsDFormatPlain,
+			prefix: "my_app.",
+			results: func() []ExecutionResult {
+				results := statsDTestResults()
+				results[0].Probe.Name = "redis cache"
+				return results
+			},
+			expected: []string{
+				"my_app.status.readiness.redis_cache:0|g",
+				"my_app.duration.readiness.redis_cache:1.5|ms",
+				"my_app.results.readiness.redis_cache.healthy:1|c",
+				"my_app.status.readiness.db:1|g",
+				"my_app.duration.readiness.db:2|ms",
+				"my_app.results.readiness.db.unhealthy." + sanitizeStatsDName(string(ConnectionErrorCode)) + ":1|c",
+			},
+		},
+		{
+			name: "cached result",
+			results: func() []ExecutionResult {
+				results := statsDTestResults()[:1]
+				results[0].Cached = true
+				return results
+			},
+			expected: []string{
+				"healthcheck.status:0|g|#kind:readiness,probe:cache",
+			},
+		},
+		{
+			name: "forced result",
+			results: func() []ExecutionResult {
+				results := statsDTestResults()[1:]
+				results[0].Override = &Override{Mode: ForcedOverride, Status: UnhealthyStatus}
+				return results
+			},
+			expected: []string{
+				"healthcheck.status:1|g|#kind:readiness,probe:db",
+			},
+		},
+		{
+			name: "disabled result",
+			results: func() []ExecutionResult {
+				results := statsDTestResults()[:1]
+				results[0].Probe.Health = SkippedStatus
+				results[0].Override = &Override{Mode: DisabledOverride}
+				return results
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := listenStatsD(t)
+
+			opts := DefaultStatsDOptions(conn.LocalAddr().String())
+			opts.Tags = tt.tags
+			if tt.format != "" {
+				opts.Format = tt.format
+			}
+			if tt.prefix != "" {
+				opts.Prefix = tt.prefix
+			}
+
+			s, err := NewStatsDMetricsService(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			s.UpdateGauge(tt.results()...)
+
+			assertStatsDLines(t, readStatsDPackets(t, conn), tt.expected)
+		})
+	}
+}
+
+func TestStatsDMetricsServiceBatchesThePackets(t *testing.T) {
+	const maxPacketSize = 120
+
+	conn := listenStatsD(t)
+
+	opts := DefaultStatsDOptions(conn.LocalAddr().String())
+	opts.MaxPacketSize = maxPacketSize
+
+	s, err := NewStatsDMetricsService(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.UpdateGauge(statsDTestResults()...)
+
+	packets := readStatsDPackets(t, conn)
+	if len(packets) < 2 {
+		t.Fatalf("expected the metrics to be split in several packets, got %q", packets)
+	}
+
+	var lines int
+	for _, packet := range packets {
+		if len(packet) > maxPacketSize {
+			t.Fatalf("expected packets of at most %d bytes, got %d: %q", maxPacketSize, len(packet), packet)
+		}
+
+		lines += strings.Count(packet, "\n") + 1
+	}
+
+	if lines != 6 {
+		t.Fatalf("expected 6 lines, got %d in %q", lines, packets)
+	}
+}
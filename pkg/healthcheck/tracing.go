@@ -0,0 +1,79 @@
+package healthcheck
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/mpdred/healthcheck/v2/pkg/healthcheck"
+
+// WithTracerProvider traces the executions of the probes with OpenTelemetry:
+// a span for every call of ExecuteProbes, with a child span for every probe.
+//
+// The span of the probe is in the context of its ProbeCheckFn,
+// so the instrumented clients (e.g. HTTP or SQL) used by the check create their spans under it.
+//
+// By default, the executions are not traced.
+func WithTracerProvider(tp trace.TracerProvider) ServiceOption {
+	return func(s *service) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// The scopes of the executions, which identify the endpoint that requested them.
+const (
+	allProbesScope      = "all"
+	selectedProbesScope = "probes"
+)
+
+// startExecutionSpan starts the span of an execution, whose scope is either the kind of the probes,
+// allProbesScope, or selectedProbesScope.
+func (s service) startExecutionSpan(ctx context.Context, scope string, probes []Probe) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "healthcheck.execute", trace.WithAttributes(
+		attribute.String("healthcheck.scope", scope),
+		attribute.Int("healthcheck.probes", len(probes)),
+	))
+}
+
+func endExecutionSpan(span trace.Span, executionResults []ExecutionResult) {
+	status := HealthyStatus
+	for _, r := range executionResults {
+		if r.IsUnhealthy() {
+			status = UnhealthyStatus
+			break
+		}
+	}
+
+	span.SetAttributes(attribute.String("healthcheck.status", string(status)))
+	span.End()
+}
+
+func (s service) startProbeSpan(ctx context.Context, p Probe) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "healthcheck.probe", trace.WithAttributes(
+		attribute.String("healthcheck.probe.name", p.Name),
+		attribute.String("healthcheck.probe.kind", string(p.Kind)),
+	))
+}
+
+// endProbeSpan records the result of the probe, after the redaction of its error.
+func endProbeSpan(span trace.Span, r ExecutionResult) {
+	span.SetAttributes(
+		attribute.String("healthcheck.probe.status", string(r.Probe.Health)),
+		attribute.Bool("healthcheck.probe.cached", r.Cached),
+	)
+
+	if r.Override != nil {
+		span.SetAttributes(attribute.String("healthcheck.probe.override", string(r.Override.Mode)))
+	}
+
+	if r.Err != nil {
+		span.SetAttributes(attribute.String("healthcheck.probe.error_code", string(ErrorCodeOf(r.Err))))
+		span.RecordError(r.Err)
+		span.SetStatus(codes.Error, r.Err.Error())
+	}
+
+	span.End()
+}
@@ -0,0 +1,131 @@
+package healthcheck
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTracedService(t *testing.T, probes ...Probe) (Service, *tracetest.InMemoryExporter) {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	probeStore := NewInMemoryProbeStore()
+	err := probeStore.Add(probes...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return NewService(probeStore, NewNoOpMetricsService(), WithTracerProvider(tp)), exporter
+}
+
+func spanAttribute(s tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range s.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+
+	return attribute.Value{}
+}
+
+func spansByName(spans tracetest.SpanStubs, name string) []tracetest.SpanStub {
+	var found []tracetest.SpanStub
+	for _, s := range spans {
+		if s.Name == name {
+			found = append(found, s)
+		}
+	}
+
+	return found
+}
+
+func TestServiceTracesTheExecutions(t *testing.T) {
+	downstreamCheck := func(ctx context.Context) error {
+		// an instrumented client creates its spans from the context of the check
+		_, span := trace.SpanFromContext(ctx).TracerProvider().Tracer("test").Start(ctx, "downstream")
+		span.End()
+
+		return nil
+	}
+
+	failingCheck := func(context.Context) error {
+		return &CheckError{Code: ConnectionErrorCode, Message: "could not connect"}
+	}
+
+	service, exporter := newTracedService(t,
+		Probe{Name: "api", Kind: ReadinessProbeKind, CheckFn: downstreamCheck},
+		Probe{Name: "db", Kind: ReadinessProbeKind, CheckFn: failingCheck},
+		Probe{Name: "app", Kind: LivenessProbeKind, CheckFn: downstreamCheck},
+	)
+
+	_, err := service.ExecuteProbesByKind(context.Background(), ReadinessProbeKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+
+	executions := spansByName(spans, "healthcheck.execute")
+	if len(executions) != 1 {
+		t.Fatalf("expected 1 execution span, got %d", len(executions))
+	}
+
+	execution := executions[0]
+	if scope := spanAttribute(execution, "healthcheck.scope").AsString(); scope != string(ReadinessProbeKind) {
+		t.Fatalf("expected the scope %q, got %q", ReadinessProbeKind, scope)
+	}
+
+	if status := spanAttribute(execution, "healthcheck.status").AsString(); status != string(UnhealthyStatus) {
+		t.Fatalf("expected the status %q, got %q", UnhealthyStatus, status)
+	}
+
+	probeSpans := map[string]tracetest.SpanStub{}
+	for _, s := range spansByName(spans, "healthcheck.probe") {
+		if s.Parent.SpanID() != execution.SpanContext.SpanID() || s.Parent.TraceID() != execution.SpanContext.TraceID() {
+			t.Fatalf("expected the probe span to be a child of the execution span")
+		}
+
+		probeSpans[spanAttribute(s, "healthcheck.probe.name").AsString()] = s
+	}
+
+	if len(probeSpans) != 2 {
+		t.Fatalf("expected the spans of the 2 readiness probes, got %d", len(probeSpans))
+	}
+
+	db := probeSpans["db"]
+	if db.Status.Code != codes.Error || len(db.Events) == 0 {
+		t.Fatalf("expected the error to be recorded in the span of the failing probe, got %+v", db.Status)
+	}
+
+	if code := spanAttribute(db, "healthcheck.probe.error_code").AsString(); code != string(ConnectionErrorCode) {
+		t.Fatalf("expected the error code %q, got %q", ConnectionErrorCode, code)
+	}
+
+	downstream := spansByName(spans, "downstream")
+	if len(downstream) != 1 || downstream[0].Parent.SpanID() != probeSpans["api"].SpanContext.SpanID() {
+		t.Fatalf("expected the downstream span to be a child of the probe span")
+	}
+}
+
+func TestServiceTracesTheScopeOfAllProbes(t *testing.T) {
+	service, exporter := newTracedService(t, Probe{Name: "app", Kind: LivenessProbeKind, CheckFn: func(context.Context) error { return nil }})
+
+	_, err := service.ExecuteAllProbes(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	executions := spansByName(exporter.GetSpans(), "healthcheck.execute")
+	if len(executions) != 1 || spanAttribute(executions[0], "healthcheck.scope").AsString() != allProbesScope {
+		t.Fatalf("expected 1 execution span with the scope %q", allProbesScope)
+	}
+}
@@ -0,0 +1,336 @@
+package healthcheck
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const WebhookSignatureHeader = "X-Healthcheck-Signature"
+
+// Notifier sends the events of the Service to an external system.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// SubscribeNotifier sends the events of the Service to the Notifier, until the Subscription is cancelled.
+//
+// The events are sent one at a time, so the events are dropped (see Subscription.Dropped)
+// if the buffer fills up while the Notifier is retrying.
+func SubscribeNotifier(service Service, notifier Notifier, bufferSize int) Subscription {
+	fn := func(e Event) {
+		err := notifier.Notify(context.Background(), e)
+		if err != nil {
+			log.Printf("error notifying %s event: %s\n", e.Type, err)
+		}
+	}
+
+	return service.SubscribeFunc(bufferSize, fn)
+}
+
+// WebhookPayload is the default JSON body of the webhook,
+// and the data available to the body template.
+type WebhookPayload struct {
+	Type           EventType         `json:"type"`
+	Time           time.Time         `json:"time"`
+	Kind           ProbeKind         `json:"kind"`
+	Probe          string            `json:"probe,omitempty"`
+	PreviousStatus ProbeHealthStatus `json:"previous_status,omitempty"`
+	Status         ProbeHealthStatus `json:"status"`
+	Error          string            `json:"error,omitempty"`
+	Code           ErrorCode         `json:"code,omitempty"`
+}
+
+type WebhookOptions struct {
+	URLs []string
+
+	// EventTypes are the events that are sent.
+	// By default, only the status changes (of the probes and of the kinds) are sent.
+	EventTypes []EventType
+
+	// MaxRetries is the number of retries after the first attempt fails
+	// with a network error, a 429 Too Many Requests, or a 5xx status code.
+	MaxRetries int
+
+	// InitialBackoff is the wait before the first retry, which is doubled for each of the next ones, up to MaxBackoff.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// DeduplicationWindow is the interval during which an event is not sent again to a URL
+	// if the last event delivered to it for the same type, kind, and probe had the same status.
+	// So when an event could not be delivered to some of the URLs, notifying it again only sends it to those.
+	DeduplicationWindow time.Duration
+
+	// Secret is used to sign the body with HMAC-SHA256.
+	// The signature is sent in the WebhookSignatureHeader, as 'sha256=<hex digest>'.
+	Secret string
+
+	// BodyTemplate is a text/template executed with a WebhookPayload, to target receivers with a specific format.
+	// The 'json' function can be used to encode values, e.g. '{"text": {{ json .Probe }}}'.
+	// By default, the WebhookPayload is sent as JSON.
+	BodyTemplate string
+	ContentType  string
+
+	Client *http.Client
+}
+
+func DefaultWebhookOptions(urls ...string) WebhookOptions {
+	return WebhookOptions{
+		URLs:                urls,
+		EventTypes:          []EventType{ProbeStatusChangedEvent, KindStatusChangedEvent},
+		MaxRetries:          3,
+		InitialBackoff:      500 * time.Millisecond,
+		MaxBackoff:          30 * time.Second,
+		DeduplicationWindow: time.Minute,
+		ContentType:         "application/json",
+		Client:              &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type webhookNotifier struct {
+	opts     WebhookOptions
+	template *template.Template
+
+	mu            sync.Mutex
+	lastDelivered map[string]deliveredEvent
+}
+
+type deliveredEvent struct {
+	status ProbeHealthStatus
+	time   time.Time
+}
+
+func (n *webhookNotifier) Notify(ctx context.Context, e Event) error {
+	if !n.isEnabled(e.Type) {
+		return nil
+	}
+
+	payload := WebhookPayload{
+		Type:           e.Type,
+		Time:           e.Time,
+		Kind:           e.Kind,
+		Probe:          e.Probe.Name,
+		PreviousStatus: e.PreviousStatus,
+		Status:         e.Status,
+	}
+
+	if e.Err != nil {
+		payload.Error = e.Err.Error()
+		payload.Code = ErrorCodeOf(e.Err)
+	}
+
+	body, err := n.render(payload)
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	for _, url := range n.opts.URLs {
+		if n.isDuplicate(url, e) {
+			continue
+		}
+
+		err := n.send(ctx, url, body)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "could not notify %s", url)
+			}
+
+			continue
+		}
+
+		n.recordDelivered(url, e)
+	}
+
+	return firstErr
+}
+
+func (n *webhookNotifier) isEnabled(t EventType) bool {
+	for _, eventType := range n.opts.EventTypes {
+		if eventType == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func deduplicationKey(url string, e Event) string {
+	return fmt.Sprintf("%s|%s|%s|%s", url, e.Type, e.Kind, e.Probe.Name)
+}
+
+// isDuplicate reports whether the last event delivered to the URL for the same type, kind, and probe
+// had the same status, within the DeduplicationWindow.
+func (n *webhookNotifier) isDuplicate(url string, e Event) bool {
+	if n.opts.DeduplicationWindow <= 0 {
+		return false
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for k, d := range n.lastDelivered {
+		if now.Sub(d.time) >= n.opts.DeduplicationWindow {
+			delete(n.lastDelivered, k)
+		}
+	}
+
+	d, ok := n.lastDelivered[deduplicationKey(url, e)]
+
+	return ok && d.status == e.Status
+}
+
+// recordDelivered is only called once the event was delivered to the URL,
+// so a failed delivery is not deduplicated when the event is sent again.
+func (n *webhookNotifier) recordDelivered(url string, e Event) {
+	if n.opts.DeduplicationWindow <= 0 {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.lastDelivered[deduplicationKey(url, e)] = deliveredEvent{
+		status: e.Status,
+		time:   time.Now(),
+	}
+}
+
+func (n *webhookNotifier) render(payload WebhookPayload) ([]byte, error) {
+	if n.template == nil {
+		return json.Marshal(payload)
+	}
+
+	var buf bytes.Buffer
+	err := n.template.Execute(&buf, payload)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not render the webhook body")
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (n *webhookNotifier) send(ctx context.Context, url string, body []byte) error {
+	backoff := n.opts.InitialBackoff
+
+	var err error
+	for attempt := 0; attempt <= n.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+
+			backoff *= 2
+			if n.opts.MaxBackoff > 0 && backoff > n.opts.MaxBackoff {
+				backoff = n.opts.MaxBackoff
+			}
+		}
+
+		var isRetryable bool
+		isRetryable, err = n.post(ctx, url, body)
+		if err == nil || !isRetryable {
+			return err
+		}
+	}
+
+	return err
+}
+
+func (n *webhookNotifier) post(ctx context.Context, url string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("Content-Type", n.opts.ContentType)
+
+	if n.opts.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(n.opts.Secret))
+		mac.Write(body)
+		req.Header.Set(WebhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := n.opts.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+
+	defer func(Body io.ReadCloser) {
+		_, _ = io.Copy(io.Discard, Body)
+		err := Body.Close()
+		if err != nil {
+			log.Println(err)
+		}
+	}(resp.Body)
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		isRetryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+		return isRetryable, errors.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	return false, nil
+}
+
+// NewWebhookNotifier creates a Notifier that POSTs the events to the webhook URLs.
+// The event types, backoffs, content type, and client that are not set are taken from DefaultWebhookOptions,
+// so you may want to start from DefaultWebhookOptions to also get the retries and the deduplication.
+func NewWebhookNotifier(opts WebhookOptions) (Notifier, error) {
+	defaults := DefaultWebhookOptions()
+	if len(opts.EventTypes) == 0 {
+		opts.EventTypes = defaults.EventTypes
+	}
+
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = defaults.InitialBackoff
+	}
+
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = defaults.MaxBackoff
+	}
+
+	if opts.ContentType == "" {
+		opts.ContentType = defaults.ContentType
+	}
+
+	if opts.Client == nil {
+		opts.Client = defaults.Client
+	}
+
+	n := &webhookNotifier{
+		opts:          opts,
+		lastDelivered: map[string]deliveredEvent{},
+	}
+
+	if opts.BodyTemplate != "" {
+		funcs := template.FuncMap{
+			"json": func(v interface{}) (string, error) {
+				b, err := json.Marshal(v)
+				return string(b), err
+			},
+		}
+
+		t, err := template.New("webhook").Funcs(funcs).Parse(opts.BodyTemplate)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid webhook body template")
+		}
+
+		n.template = t
+	}
+
+	return n, nil
+}
@@ -0,0 +1,250 @@
+package healthcheck
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver responds with the failures first, and then records the payloads.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	attempts int
+	failures []int
+	payloads []WebhookPayload
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	rcv.attempts++
+	if len(rcv.failures) > 0 {
+		w.WriteHeader(rcv.failures[0])
+		rcv.failures = rcv.failures[1:]
+		return
+	}
+
+	var payload WebhookPayload
+	_ = json.NewDecoder(r.Body).Decode(&payload)
+	rcv.payloads = append(rcv.payloads, payload)
+}
+
+func (rcv *webhookReceiver) statuses() []ProbeHealthStatus {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+
+	statuses := make([]ProbeHealthStatus, 0, len(rcv.payloads))
+	for _, p := range rcv.payloads {
+		statuses = append(statuses, p.Status)
+	}
+
+	return statuses
+}
+
+func statusChangedEvent(status ProbeHealthStatus) Event {
+	e := Event{
+		Type:   ProbeStatusChangedEvent,
+		Time:   time.Now(),
+		Kind:   ReadinessProbeKind,
+		Probe:  Probe{Name: "db"},
+		Status: status,
+	}
+
+	return e
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxRetries int
+		failures   []int
+		attempts   int
+		delivered  bool
+	}{
+		{
+			name:       "success",
+			maxRetries: 3,
+			attempts:   1,
+			delivered:  true,
+		},
+		{
+			name:       "retryable failures",
+			maxRetries: 3,
+			failures:   []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			attempts:   3,
+			delivered:  true,
+		},
+		{
+			name:       "client error",
+			maxRetries: 3,
+			failures:   []int{http.StatusBadRequest},
+			attempts:   1,
+		},
+		{
+			name:       "too many failures",
+			maxRetries: 1,
+			failures:   []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway},
+			attempts:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv := &webhookReceiver{failures: tt.failures}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			opts := DefaultWebhookOptions(srv.URL)
+			opts.MaxRetries = tt.maxRetries
+			opts.InitialBackoff = time.Millisecond
+
+			n, err := NewWebhookNotifier(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = n.Notify(context.Background(), statusChangedEvent(UnhealthyStatus))
+			if (err == nil) != tt.delivered {
+				t.Fatalf("expected delivered=%t, got the error %v", tt.delivered, err)
+			}
+
+			if rcv.attempts != tt.attempts {
+				t.Fatalf("expected %d attempts, got %d", tt.attempts, rcv.attempts)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierSignsTheBody(t *testing.T) {
+	var signature string
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(WebhookSignatureHeader)
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	opts := DefaultWebhookOptions(srv.URL)
+	opts.Secret = "s3cr3t"
+
+	n, err := NewWebhookNotifier(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = n.Notify(context.Background(), statusChangedEvent(UnhealthyStatus))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac := hmac.New(sha256.New, []byte(opts.Secret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	if signature != expected {
+		t.Fatalf("expected the signature %q, got %q", expected, signature)
+	}
+}
+
+func TestWebhookNotifierDeduplicates(t *testing.T) {
+	const (
+		h = HealthyStatus
+		u = UnhealthyStatus
+	)
+
+	tests := []struct {
+		name      string
+		window    time.Duration
+		statuses  []ProbeHealthStatus
+		delivered []ProbeHealthStatus
+	}{
+		{
+			name:      "repeated status",
+			window:    time.Minute,
+			statuses:  []ProbeHealthStatus{u, u, h, u, u},
+			delivered: []ProbeHealthStatus{u, h, u},
+		},
+		{
+			name:      "no deduplication",
+			statuses:  []ProbeHealthStatus{u, u, h},
+			delivered: []ProbeHealthStatus{u, u, h},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv := &webhookReceiver{}
+			srv := httptest.NewServer(rcv)
+			defer srv.Close()
+
+			opts := DefaultWebhookOptions(srv.URL)
+			opts.DeduplicationWindow = tt.window
+
+			n, err := NewWebhookNotifier(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, status := range tt.statuses {
+				err := n.Notify(context.Background(), statusChangedEvent(status))
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got := rcv.statuses()
+			if len(got) != len(tt.delivered) {
+				t.Fatalf("expected %v, got %v", tt.delivered, got)
+			}
+
+			for i := range got {
+				if got[i] != tt.delivered[i] {
+					t.Fatalf("expected %v, got %v", tt.delivered, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierResendsOnlyToTheFailedURLs(t *testing.T) {
+	ok := &webhookReceiver{}
+	okSrv := httptest.NewServer(ok)
+	defer okSrv.Close()
+
+	failing := &webhookReceiver{failures: []int{http.StatusServiceUnavailable}}
+	failingSrv := httptest.NewServer(failing)
+	defer failingSrv.Close()
+
+	opts := DefaultWebhookOptions(okSrv.URL, failingSrv.URL)
+	opts.MaxRetries = 0
+
+	n, err := NewWebhookNotifier(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := statusChangedEvent(UnhealthyStatus)
+
+	err = n.Notify(context.Background(), e)
+	if err == nil {
+		t.Fatal("expected an error for the failing URL")
+	}
+
+	err = n.Notify(context.Background(), e)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ok.statuses()) != 1 || len(failing.statuses()) != 1 {
+		t.Fatalf("expected the event to be delivered once to each URL, got %d and %d", len(ok.statuses()), len(failing.statuses()))
+	}
+}